@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/jasxu/fi_system/internal/config"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/mcp"
+	"github.com/jasxu/fi_system/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm/logger"
+)
+
+// MCP Server：通过 stdio 与 Claude Desktop 通信，stdout 只能输出协议消息，日志全部写到 stderr
+//
+// mcp_servers.json 示例：
+//
+//	{
+//	  "mcpServers": {
+//	    "fi_system": {
+//	      "command": "/path/to/fi-mcp",
+//	      "env": {
+//	        "DB_PATH": "/path/to/data/finance.db",
+//	        "MCP_USERNAME": "alice",
+//	        "MCP_PASSWORD": "..."
+//	      }
+//	    }
+//	  }
+//	}
+func main() {
+	log.SetOutput(os.Stderr)
+
+	// 加载配置
+	cfg := config.Load()
+	if cfg.MCPUsername == "" || cfg.MCPPassword == "" {
+		log.Fatal("MCP_USERNAME and MCP_PASSWORD must be set")
+	}
+
+	// 初始化数据库
+	sqlLogger := logger.New(log.New(os.Stderr, "", log.LstdFlags), logger.Config{LogLevel: logger.Warn})
+	if err := database.InitializeWithLogger(cfg.DBPath, sqlLogger); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	// 以配置的用户身份登录
+	var user models.User
+	if err := database.DB.Where("username = ?", cfg.MCPUsername).First(&user).Error; err != nil {
+		log.Fatalf("MCP user %q not found", cfg.MCPUsername)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(cfg.MCPPassword)); err != nil {
+		log.Fatalf("Invalid credentials for MCP user %q", cfg.MCPUsername)
+	}
+
+	log.Printf("MCP server started for user %s", user.Username)
+	if err := mcp.NewServer(user.ID).Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("MCP server stopped: %v", err)
+	}
+}
@@ -1,16 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jasxu/fi_system/internal/config"
 	"github.com/jasxu/fi_system/internal/database"
 	"github.com/jasxu/fi_system/internal/handlers"
 	"github.com/jasxu/fi_system/internal/middleware"
+	"github.com/jasxu/fi_system/internal/scheduler"
 )
 
 func main() {
@@ -55,23 +58,125 @@ func main() {
 	protected := v1.Group("")
 	protected.Use(middleware.AuthMiddleware(cfg))
 	{
+		// 用户设置路由
+		userHandler := handlers.NewUserHandler()
+		protected.GET("/profile", userHandler.GetProfile)
+		protected.PUT("/profile", userHandler.UpdateProfile)
+
 		// 账户路由
 		accountHandler := handlers.NewAccountHandler()
 		protected.GET("/accounts", accountHandler.GetAccounts)
 		protected.GET("/accounts/:id", accountHandler.GetAccount)
+		protected.GET("/accounts/:id/balance-history", accountHandler.GetBalanceHistory)
 		protected.POST("/accounts", accountHandler.CreateAccount)
 		protected.PUT("/accounts/:id", accountHandler.UpdateAccount)
 		protected.DELETE("/accounts/:id", accountHandler.DeleteAccount)
 
+		// 汇率路由
+		exchangeRateHandler := handlers.NewExchangeRateHandler()
+		protected.GET("/exchange-rates", exchangeRateHandler.GetExchangeRates)
+		protected.POST("/exchange-rates", exchangeRateHandler.SetExchangeRate)
+		protected.POST("/exchange-rates/import", exchangeRateHandler.ImportExchangeRates)
+		protected.DELETE("/exchange-rates/:id", exchangeRateHandler.DeleteExchangeRate)
+
+		// 分类路由
+		categoryHandler := handlers.NewCategoryHandler()
+		protected.GET("/categories", categoryHandler.GetCategories)
+		protected.GET("/categories/:id", categoryHandler.GetCategory)
+		protected.POST("/categories", categoryHandler.CreateCategory)
+		protected.PUT("/categories/:id", categoryHandler.UpdateCategory)
+		protected.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		protected.POST("/categories/:id/merge", categoryHandler.MergeCategory)
+
+		// 标签路由
+		tagHandler := handlers.NewTagHandler()
+		protected.GET("/tags", tagHandler.GetTags)
+		protected.POST("/tags", tagHandler.CreateTag)
+		protected.PUT("/tags/:id", tagHandler.UpdateTag)
+		protected.DELETE("/tags/:id", tagHandler.DeleteTag)
+
+		// 规则路由
+		ruleHandler := handlers.NewRuleHandler()
+		protected.GET("/rules", ruleHandler.GetRules)
+		protected.GET("/rules/:id", ruleHandler.GetRule)
+		protected.POST("/rules", ruleHandler.CreateRule)
+		protected.POST("/rules/apply", ruleHandler.ApplyRules)
+		protected.PUT("/rules/:id", ruleHandler.UpdateRule)
+		protected.DELETE("/rules/:id", ruleHandler.DeleteRule)
+
 		// 交易路由
 		transactionHandler := handlers.NewTransactionHandler()
 		protected.GET("/transactions", transactionHandler.GetTransactions)
 		protected.GET("/transactions/:id", transactionHandler.GetTransaction)
 		protected.POST("/transactions", transactionHandler.CreateTransaction)
+		protected.POST("/transactions/import", transactionHandler.ImportTransactions)
+		protected.POST("/transactions/batch", transactionHandler.BatchTransactions)
 		protected.PUT("/transactions/:id", transactionHandler.UpdateTransaction)
 		protected.DELETE("/transactions/:id", transactionHandler.DeleteTransaction)
+		protected.POST("/transactions/:id/tags", tagHandler.AddTransactionTags)
+		protected.DELETE("/transactions/:id/tags/:tag_id", tagHandler.RemoveTransactionTag)
+
+		// 预算路由
+		budgetHandler := handlers.NewBudgetHandler()
+		protected.GET("/budgets", budgetHandler.GetBudgets)
+		protected.GET("/budgets/status", budgetHandler.GetBudgetStatus)
+		protected.GET("/budgets/:id", budgetHandler.GetBudget)
+		protected.POST("/budgets", budgetHandler.CreateBudget)
+		protected.PUT("/budgets/:id", budgetHandler.UpdateBudget)
+		protected.DELETE("/budgets/:id", budgetHandler.DeleteBudget)
+
+		// 周期交易路由
+		recurringHandler := handlers.NewRecurringHandler()
+		protected.GET("/recurring-transactions", recurringHandler.GetRecurringTransactions)
+		protected.GET("/recurring-transactions/:id", recurringHandler.GetRecurringTransaction)
+		protected.POST("/recurring-transactions", recurringHandler.CreateRecurringTransaction)
+		protected.PUT("/recurring-transactions/:id", recurringHandler.UpdateRecurringTransaction)
+		protected.DELETE("/recurring-transactions/:id", recurringHandler.DeleteRecurringTransaction)
+
+		// 持仓路由
+		holdingHandler := handlers.NewHoldingHandler()
+		protected.GET("/holdings", holdingHandler.GetHoldings)
+		protected.GET("/holdings/:id", holdingHandler.GetHolding)
+		protected.POST("/holdings", holdingHandler.CreateHolding)
+		protected.PUT("/holdings/:id", holdingHandler.UpdateHolding)
+		protected.DELETE("/holdings/:id", holdingHandler.DeleteHolding)
+
+		// 对账路由
+		reconciliationHandler := handlers.NewReconciliationHandler()
+		protected.GET("/reconciliations", reconciliationHandler.GetReconciliations)
+		protected.POST("/reconciliations", reconciliationHandler.CreateReconciliation)
+		protected.GET("/reconciliations/:id", reconciliationHandler.GetReconciliation)
+		protected.POST("/reconciliations/:id/complete", reconciliationHandler.CompleteReconciliation)
+		protected.DELETE("/reconciliations/:id", reconciliationHandler.DeleteReconciliation)
+
+		// 报表路由
+		reportHandler := handlers.NewReportHandler()
+		protected.GET("/reports/daily", reportHandler.GetDailyReport)
+		protected.GET("/reports/monthly", reportHandler.GetMonthlyReport)
+		protected.GET("/reports/category-summary", reportHandler.GetCategorySummary)
+		protected.GET("/reports/net-worth", reportHandler.GetNetWorth)
+
+		// 定投路由
+		investmentHandler := handlers.NewInvestmentHandler()
+		protected.GET("/investment/settings", investmentHandler.GetSettings)
+		protected.PUT("/investment/settings", investmentHandler.UpdateSettings)
+		protected.GET("/investment/stage", investmentHandler.GetStage)
+		protected.GET("/investment/actions", investmentHandler.GetActions)
+		protected.POST("/investment/actions/:id/execute", investmentHandler.ExecuteAction)
 	}
 
+	// 周期交易调度：启动时立即执行一次，补齐停机期间到期的交易
+	recurringScheduler := scheduler.New(time.Hour, func(ctx context.Context) {
+		created, err := handlers.RunRecurringTransactions(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to run recurring transactions: %v", err)
+		}
+		if created > 0 {
+			log.Printf("Created %d recurring transactions", created)
+		}
+	})
+	recurringScheduler.Start()
+
 	// 启动服务器
 	log.Printf("Server starting on %s", cfg.ServerPort)
 	log.Printf("Database: %s", cfg.DBPath)
@@ -89,4 +194,5 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	recurringScheduler.Stop()
 }
@@ -8,6 +8,10 @@ type Config struct {
 	DBPath     string
 	JWTSecret  string
 	ServerPort string
+
+	// MCP Server 以该用户身份访问数据
+	MCPUsername string
+	MCPPassword string
 }
 
 func Load() *Config {
@@ -15,6 +19,9 @@ func Load() *Config {
 		DBPath:     getEnv("DB_PATH", "../data/finance.db"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key-change-in-production"),
 		ServerPort: getEnv("SERVER_PORT", ":8080"),
+
+		MCPUsername: getEnv("MCP_USERNAME", ""),
+		MCPPassword: getEnv("MCP_PASSWORD", ""),
 	}
 	return cfg
 }
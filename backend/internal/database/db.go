@@ -14,11 +14,16 @@ var DB *gorm.DB
 
 // Initialize 初始化数据库连接
 func Initialize(dbPath string) error {
+	return InitializeWithLogger(dbPath, logger.Default.LogMode(logger.Info))
+}
+
+// InitializeWithLogger 使用指定的 SQL 日志初始化数据库连接（MCP 模式下 stdout 被协议占用）
+func InitializeWithLogger(dbPath string, sqlLogger logger.Interface) error {
 	var err error
 
 	// 打开数据库连接
 	DB, err = gorm.Open(sqlite.Open(dbPath+"?_foreign_keys=on"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:                                   sqlLogger,
 		DisableForeignKeyConstraintWhenMigrating: false,
 	})
 	if err != nil {
@@ -38,12 +43,33 @@ func Initialize(dbPath string) error {
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Account{},
+		&models.ExchangeRate{},
+		&models.Category{},
+		&models.Tag{},
+		&models.Rule{},
 		&models.Transaction{},
+		&models.TransactionSplit{},
+		&models.Reconciliation{},
+		&models.RecurringTransaction{},
+		&models.Budget{},
+		&models.Holding{},
+		&models.InvestmentSettings{},
+		&models.InvestmentAction{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	// 交易全文检索（先于数据迁移，保证迁移中的交易更新同步到索引）
+	if err := setupTransactionSearch(DB); err != nil {
+		return fmt.Errorf("failed to set up transaction search: %w", err)
+	}
+
+	// 数据迁移
+	if err := runMigrations(DB); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
 	log.Println("Database initialized successfully")
 	return nil
 }
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// schemaMigration 记录已执行的数据迁移，保证每个迁移只执行一次
+type schemaMigration struct {
+	ID        string `gorm:"primarykey;size:100"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// migration AutoMigrate 无法完成的数据迁移（在 AutoMigrate 之后执行）
+type migration struct {
+	ID  string
+	Run func(tx *gorm.DB) error
+}
+
+var migrations = []migration{
+	{ID: "20261017_money_minor_units", Run: migrateMoneyToMinorUnits},
+	{ID: "20261017_categories", Run: migrateCategories},
+	{ID: "20261017_exchange_rate_unique_pair", Run: migrateExchangeRateUniquePair},
+}
+
+// runMigrations 按顺序执行尚未执行过的迁移，每个迁移在独立事务中完成
+func runMigrations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+		log.Printf("Applied migration %s", m.ID)
+	}
+	return nil
+}
+
+// migrateMoneyToMinorUnits 将原 decimal(20,2) 金额（元）转换为整数分
+func migrateMoneyToMinorUnits(tx *gorm.DB) error {
+	columns := map[string][]string{
+		"transactions":        {"amount"},
+		"holdings":            {"cost_basis_total", "market_value"},
+		"investment_settings": {"monthly_income", "monthly_expense", "cash_reserve_target", "buffer_reserve_target", "base_investment_amount", "boost_investment_amount"},
+		"investment_actions":  {"amount"},
+	}
+
+	for table, cols := range columns {
+		for _, col := range cols {
+			// decimal(20,2) 最多两位小数，ROUND 消除 REAL 存储的表示误差，转换无损
+			sql := fmt.Sprintf("UPDATE `%s` SET `%s` = CAST(ROUND(`%s` * 100) AS INTEGER) WHERE `%s` IS NOT NULL", table, col, col, col)
+			if err := tx.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateCategories 由交易中已有的分类文本创建分类（去除首尾空格后合并）并回填 category_id
+func migrateCategories(tx *gorm.DB) error {
+	kind := "CASE WHEN type = 'income' THEN 'income' ELSE 'expense' END"
+	now := time.Now()
+
+	if err := tx.Exec(`INSERT INTO categories (user_id, name, kind, archived, created_at, updated_at)
+		SELECT user_id, TRIM(category), `+kind+`, false, ?, ?
+		FROM transactions
+		WHERE TRIM(COALESCE(category, '')) <> ''
+		GROUP BY user_id, TRIM(category), `+kind, now, now).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec(`UPDATE transactions SET
+			category = TRIM(category),
+			category_id = (
+				SELECT c.id FROM categories c
+				WHERE c.user_id = transactions.user_id AND c.name = TRIM(transactions.category)
+					AND c.kind = ` + kind + ` AND c.deleted_at IS NULL
+				ORDER BY c.id LIMIT 1
+			)
+		WHERE TRIM(COALESCE(category, '')) <> '' AND category_id IS NULL`).Error
+}
+
+// migrateExchangeRateUniquePair 同一天同一币种对的重复汇率只保留一条（优先未删除、最新录入的），
+// 并将 idx_exchange_rate_pair 重建为唯一索引（AutoMigrate 不会修改已存在的同名索引）
+func migrateExchangeRateUniquePair(tx *gorm.DB) error {
+	if err := tx.Exec(`DELETE FROM exchange_rates WHERE id NOT IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY user_id, from_currency, to_currency, date
+					ORDER BY deleted_at IS NOT NULL, id DESC
+				) AS rn
+				FROM exchange_rates
+			) WHERE rn = 1
+		)`).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DROP INDEX IF EXISTS idx_exchange_rate_pair").Error; err != nil {
+		return err
+	}
+	return tx.Exec("CREATE UNIQUE INDEX idx_exchange_rate_pair ON exchange_rates (user_id, from_currency, to_currency, date)").Error
+}
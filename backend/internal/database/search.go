@@ -0,0 +1,85 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// SearchEnabled 交易全文检索（FTS5）是否可用。
+// FTS5 需要以 -tags sqlite_fts5 编译 go-sqlite3，不可用时交易搜索退化为 LIKE 扫描。
+var SearchEnabled bool
+
+// transactionSearchTriggers 保持 transactions_fts 与 transactions 同步；
+// 软删除是对 deleted_at 的 UPDATE，因此只索引 deleted_at 为空的行
+var transactionSearchTriggers = map[string]string{
+	"transactions_fts_ai": `CREATE TRIGGER transactions_fts_ai AFTER INSERT ON transactions
+		WHEN new.deleted_at IS NULL BEGIN
+			INSERT INTO transactions_fts(rowid, merchant, description, category)
+			VALUES (new.id, new.merchant, new.description, new.category);
+		END`,
+	"transactions_fts_ad": `CREATE TRIGGER transactions_fts_ad AFTER DELETE ON transactions BEGIN
+			DELETE FROM transactions_fts WHERE rowid = old.id;
+		END`,
+	"transactions_fts_au": `CREATE TRIGGER transactions_fts_au AFTER UPDATE ON transactions BEGIN
+			DELETE FROM transactions_fts WHERE rowid = old.id;
+			INSERT INTO transactions_fts(rowid, merchant, description, category)
+			SELECT new.id, new.merchant, new.description, new.category WHERE new.deleted_at IS NULL;
+		END`,
+}
+
+// setupTransactionSearch 创建交易全文索引（trigram 分词，中文商户名可按子串匹配）
+func setupTransactionSearch(db *gorm.DB) error {
+	var fts5 int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if fts5 == 0 {
+		// 删除旧触发器，否则缺少 fts5 模块时所有交易写入都会失败
+		for name := range transactionSearchTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		SearchEnabled = false
+		log.Println("SQLite FTS5 not available (build with -tags sqlite_fts5), transaction search falls back to LIKE")
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS transactions_fts
+			USING fts5(merchant, description, category, tokenize = 'trigram')`).Error; err != nil {
+			return err
+		}
+
+		var existing int64
+		if err := tx.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'transactions_fts_%'").
+			Scan(&existing).Error; err != nil {
+			return err
+		}
+		if existing == int64(len(transactionSearchTriggers)) {
+			SearchEnabled = true
+			return nil
+		}
+
+		// 首次创建或触发器缺失期间可能有未同步的写入，重建索引
+		for name, ddl := range transactionSearchTriggers {
+			if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(ddl).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM transactions_fts").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`INSERT INTO transactions_fts(rowid, merchant, description, category)
+			SELECT id, merchant, description, category FROM transactions WHERE deleted_at IS NULL`).Error; err != nil {
+			return err
+		}
+		SearchEnabled = true
+		log.Println("Rebuilt transaction search index")
+		return nil
+	})
+}
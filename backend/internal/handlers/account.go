@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jasxu/fi_system/internal/database"
@@ -15,45 +17,73 @@ func NewAccountHandler() *AccountHandler {
 }
 
 type CreateAccountRequest struct {
-	Name           string                 `json:"name" binding:"required"`
-	Type           models.AccountType     `json:"type" binding:"required"`
-	Currency       string                 `json:"currency"`
-	LiquidityLevel models.LiquidityLevel  `json:"liquidity_level"`
+	Name               string                `json:"name" binding:"required"`
+	Type               models.AccountType    `json:"type" binding:"required"`
+	Currency           string                `json:"currency"`
+	LiquidityLevel     models.LiquidityLevel `json:"liquidity_level"`
+	OpeningBalance     models.Money          `json:"opening_balance"`
+	OpeningBalanceDate string                `json:"opening_balance_date"` // YYYY-MM-DD
 }
 
 type UpdateAccountRequest struct {
-	Name           string                 `json:"name"`
-	Type           models.AccountType     `json:"type"`
-	Currency       string                 `json:"currency"`
-	LiquidityLevel models.LiquidityLevel  `json:"liquidity_level"`
+	Name               string                `json:"name"`
+	Type               models.AccountType    `json:"type"`
+	Currency           string                `json:"currency"`
+	LiquidityLevel     models.LiquidityLevel `json:"liquidity_level"`
+	OpeningBalance     *models.Money         `json:"opening_balance"`
+	OpeningBalanceDate *string               `json:"opening_balance_date"` // YYYY-MM-DD，空字符串表示清除
 }
 
 type AccountWithBalance struct {
 	models.Account
-	Balance float64 `json:"balance"`
+	Balance      models.Money  `json:"balance"`
+	BaseCurrency string        `json:"base_currency"`
+	BaseBalance  *models.Money `json:"base_balance"` // 按当前汇率折算的本位币余额，缺少汇率时为空
 }
 
 // GetAccounts 获取账户列表
 func (h *AccountHandler) GetAccounts(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	var accounts []models.Account
-	if err := database.DB.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+	accountsWithBalance, err := ListAccountsWithBalance(userID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch accounts"})
 		return
 	}
 
-	// 计算每个账户的余额
+	c.JSON(http.StatusOK, accountsWithBalance)
+}
+
+// ListAccountsWithBalance 获取用户的全部账户及余额
+func ListAccountsWithBalance(userID uint) ([]AccountWithBalance, error) {
+	var accounts []models.Account
+	if err := database.DB.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 全部账户的余额由一次分组查询得到
+	balances, err := accountBalances(userID, accounts, time.Time{})
+	if err != nil {
+		return nil, err
+	}
 	accountsWithBalance := make([]AccountWithBalance, len(accounts))
 	for i, acc := range accounts {
-		balance := calculateAccountBalance(acc.ID)
-		accountsWithBalance[i] = AccountWithBalance{
-			Account: acc,
-			Balance: balance,
-		}
+		accountsWithBalance[i] = withBalance(acc, balances[acc.ID], cv)
 	}
+	return accountsWithBalance, nil
+}
 
-	c.JSON(http.StatusOK, accountsWithBalance)
+// GetAccountWithBalance 获取用户的单个账户及余额
+func GetAccountWithBalance(userID, accountID uint) (*AccountWithBalance, error) {
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusNotFound, Message: "account not found"}
+	}
+	return accountWithBalance(account)
 }
 
 // GetAccount 获取单个账户
@@ -61,17 +91,19 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 	userID := c.GetUint("user_id")
 	accountID := c.Param("id")
 
-	var account models.Account
-	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+	id, err := strconv.ParseUint(accountID, 10, 64)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
 		return
 	}
 
-	balance := calculateAccountBalance(account.ID)
-	c.JSON(http.StatusOK, AccountWithBalance{
-		Account: account,
-		Balance: balance,
-	})
+	account, err := GetAccountWithBalance(userID, uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, account)
 }
 
 // CreateAccount 创建账户
@@ -90,12 +122,28 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		Type:           req.Type,
 		Currency:       req.Currency,
 		LiquidityLevel: req.LiquidityLevel,
+		OpeningBalance: req.OpeningBalance,
+	}
+
+	if req.OpeningBalanceDate != "" {
+		openingDate, err := time.Parse("2006-01-02", req.OpeningBalanceDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid opening_balance_date format, use YYYY-MM-DD"})
+			return
+		}
+		account.OpeningBalanceDate = &openingDate
 	}
 
 	// 设置默认值
 	if account.Currency == "" {
-		account.Currency = "CNY"
+		account.Currency = defaultCurrency
 	}
+	currency, err := normalizeCurrency(account.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	account.Currency = currency
 	if account.LiquidityLevel == "" {
 		account.LiquidityLevel = models.LiquidityLow
 	}
@@ -105,10 +153,12 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, AccountWithBalance{
-		Account: account,
-		Balance: 0,
-	})
+	result, err := accountWithBalance(account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate balance"})
+		return
+	}
+	c.JSON(http.StatusCreated, result)
 }
 
 // UpdateAccount 更新账户
@@ -136,22 +186,43 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		account.Type = req.Type
 	}
 	if req.Currency != "" {
-		account.Currency = req.Currency
+		currency, err := normalizeCurrency(req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		account.Currency = currency
 	}
 	if req.LiquidityLevel != "" {
 		account.LiquidityLevel = req.LiquidityLevel
 	}
+	if req.OpeningBalance != nil {
+		account.OpeningBalance = *req.OpeningBalance
+	}
+	if req.OpeningBalanceDate != nil {
+		if *req.OpeningBalanceDate == "" {
+			account.OpeningBalanceDate = nil
+		} else {
+			openingDate, err := time.Parse("2006-01-02", *req.OpeningBalanceDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid opening_balance_date format, use YYYY-MM-DD"})
+				return
+			}
+			account.OpeningBalanceDate = &openingDate
+		}
+	}
 
 	if err := database.DB.Save(&account).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update account"})
 		return
 	}
 
-	balance := calculateAccountBalance(account.ID)
-	c.JSON(http.StatusOK, AccountWithBalance{
-		Account: account,
-		Balance: balance,
-	})
+	result, err := accountWithBalance(account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate balance"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // DeleteAccount 删除账户（软删除）
@@ -173,27 +244,136 @@ func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "account deleted successfully"})
 }
 
-// calculateAccountBalance 计算账户余额
-func calculateAccountBalance(accountID uint) float64 {
-	var balance float64
-
-	// 使用修正后的 SQL 查询
-	query := `
-		SELECT COALESCE(SUM(
-			CASE
-				WHEN type = 'income' AND account_id = ? THEN amount
-				WHEN type = 'expense' AND account_id = ? THEN -amount
-				WHEN type = 'transfer' AND account_id = ? THEN -amount
-				WHEN type = 'transfer' AND to_account_id = ? THEN amount
-				WHEN type = 'investment' AND account_id = ? THEN -amount
-				ELSE 0
-			END
-		), 0) AS balance
-		FROM transactions
-		WHERE (account_id = ? OR to_account_id = ?)
-		  AND deleted_at IS NULL
-	`
-
-	database.DB.Raw(query, accountID, accountID, accountID, accountID, accountID, accountID, accountID).Scan(&balance)
-	return balance
+// accountWithBalance 计算单个账户的余额，与账户列表使用同一查询
+func accountWithBalance(account models.Account) (*AccountWithBalance, error) {
+	cv, err := loadCurrencyConverter(account.UserID)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := accountBalances(account.UserID, []models.Account{account}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	result := withBalance(account, balances[account.ID], cv)
+	return &result, nil
+}
+
+// withBalance 附加账户余额，并按当前汇率折算为本位币
+func withBalance(account models.Account, balance models.Money, cv *currencyConverter) AccountWithBalance {
+	result := AccountWithBalance{Account: account, Balance: balance, BaseCurrency: cv.base}
+	if base, ok := cv.convert(balance, account.Currency, time.Now()); ok {
+		result.BaseBalance = &base
+	}
+	return result
+}
+
+// maxBalancePoints 余额历史单次返回的点数上限
+const maxBalancePoints = 1000
+
+// BalancePoint 余额序列中的一个点：截至 Date 当天（含）的余额及该区间的流入流出
+type BalancePoint struct {
+	Date    string       `json:"date"`
+	Inflow  models.Money `json:"inflow"`
+	Outflow models.Money `json:"outflow"`
+	Balance models.Money `json:"balance"`
+}
+
+// BalanceHistory 账户余额历史
+type BalanceHistory struct {
+	AccountID       uint           `json:"account_id"`
+	Currency        string         `json:"currency"`
+	Interval        string         `json:"interval"`
+	Start           string         `json:"start"`
+	End             string         `json:"end"`
+	StartingBalance models.Money   `json:"starting_balance"` // start 前一天结束时的余额
+	Points          []BalancePoint `json:"points"`
+}
+
+// GetBalanceHistory 账户余额历史（start、end 为 YYYY-MM-DD，interval 为 day/week/month），
+// 每个点是区间最后一天的余额；默认最近 30 天、12 周或 12 个月
+func (h *AccountHandler) GetBalanceHistory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	accountID := c.Param("id")
+
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+		return
+	}
+
+	start, end, interval, err := parseBalanceRange(c, "day")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	closes, err := balanceIntervalCloses(start, end, interval)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	startingBalances, series, err := balanceSeries(userID, []models.Account{account}, start, closes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch balance history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, BalanceHistory{
+		AccountID:       account.ID,
+		Currency:        account.Currency,
+		Interval:        interval,
+		Start:           start.Format("2006-01-02"),
+		End:             end.Format("2006-01-02"),
+		StartingBalance: startingBalances[account.ID],
+		Points:          series[account.ID],
+	})
+}
+
+// parseBalanceRange 解析 start、end、interval 查询参数；未指定 start 时取最近 30 天、12 周或 12 个月
+func parseBalanceRange(c *gin.Context, defaultInterval string) (start, end time.Time, interval string, err error) {
+	interval = c.DefaultQuery("interval", defaultInterval)
+	end = time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("end"); raw != "" {
+		if end, err = time.Parse("2006-01-02", raw); err != nil {
+			return start, end, interval, badRequest("invalid end format, use YYYY-MM-DD")
+		}
+	}
+	switch interval {
+	case "day":
+		start = end.AddDate(0, 0, -29)
+	case "week":
+		start = end.AddDate(0, 0, -7*12+1)
+	case "month":
+		start = end.AddDate(0, -12, 1)
+	default:
+		return start, end, interval, badRequest("interval must be day, week or month")
+	}
+	if raw := c.Query("start"); raw != "" {
+		if start, err = time.Parse("2006-01-02", raw); err != nil {
+			return start, end, interval, badRequest("invalid start format, use YYYY-MM-DD")
+		}
+	}
+	if end.Before(start) {
+		return start, end, interval, badRequest("end must not be before start")
+	}
+	return start, end, interval, nil
+}
+
+// balanceIntervalCloses 将 [start, end] 按 interval 切分（周从周一开始），返回各区间的最后一天
+func balanceIntervalCloses(start, end time.Time, interval string) ([]time.Time, error) {
+	var closes []time.Time
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		last := next.After(end) ||
+			interval == "day" ||
+			(interval == "week" && next.Weekday() == time.Monday) ||
+			(interval == "month" && next.Day() == 1)
+		if last {
+			closes = append(closes, day)
+			if len(closes) > maxBalancePoints {
+				return nil, badRequest("too many points, use a shorter range or a larger interval")
+			}
+		}
+	}
+	return closes, nil
 }
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// balanceLegsSQL 每笔交易对所涉账户余额的影响，一行对应一个账户（流入为正、流出为负）。
+// 转账拆为转出、转入两行，跨币种转账的转入使用 to_amount；转出转入为同一账户时只计转出。
+// 期初日期之前的交易已含在期初余额中，不计入。
+const balanceLegsSQL = `
+	SELECT legs.account_id, legs.transaction_date, legs.amount
+	FROM (
+		SELECT account_id, transaction_date,
+			CASE
+				WHEN type = 'income' THEN amount
+				WHEN type IN ('expense', 'transfer', 'investment') THEN -amount
+				ELSE 0
+			END AS amount
+		FROM transactions
+		WHERE user_id = @user AND account_id IN @ids AND deleted_at IS NULL
+		UNION ALL
+		SELECT to_account_id, transaction_date, COALESCE(to_amount, amount)
+		FROM transactions
+		WHERE user_id = @user AND to_account_id IN @ids AND deleted_at IS NULL
+		  AND type = 'transfer' AND to_account_id <> account_id
+	) legs
+	JOIN accounts ON accounts.id = legs.account_id
+	WHERE (accounts.opening_balance_date IS NULL OR legs.transaction_date >= accounts.opening_balance_date)
+`
+
+// queryBalanceLegs 对 accounts 的余额变动按 groupBy 分组汇总，限定 [start, end) 区间（零值表示不限）
+func queryBalanceLegs(dest interface{}, columns, groupBy string, userID uint, accounts []models.Account, start, end time.Time) error {
+	ids := make([]uint, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+
+	query := "SELECT " + columns + " FROM (" + balanceLegsSQL + ") legs WHERE 1 = 1"
+	if !start.IsZero() {
+		query += " AND legs.transaction_date >= @start"
+	}
+	if !end.IsZero() {
+		query += " AND legs.transaction_date < @end"
+	}
+	query += " GROUP BY " + groupBy + " ORDER BY " + groupBy
+
+	return database.DB.Raw(query,
+		sql.Named("user", userID), sql.Named("ids", ids), sql.Named("start", start), sql.Named("end", end),
+	).Scan(dest).Error
+}
+
+// accountBalances 用一次分组查询计算多个账户的余额：期初余额 + 期初日期（含）之后的交易。
+// before 非零值时只计 before 之前（不含）的交易，即 before 前一天结束时的余额。
+func accountBalances(userID uint, accounts []models.Account, before time.Time) (map[uint]models.Money, error) {
+	balances := make(map[uint]models.Money, len(accounts))
+	if len(accounts) == 0 {
+		return balances, nil
+	}
+
+	var rows []struct {
+		AccountID uint
+		Balance   models.Money
+	}
+	if err := queryBalanceLegs(&rows, "account_id, COALESCE(SUM(amount), 0) AS balance", "account_id",
+		userID, accounts, time.Time{}, before); err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		balances[account.ID] = account.OpeningBalance
+	}
+	for _, row := range rows {
+		balances[row.AccountID] += row.Balance
+	}
+	return balances, nil
+}
+
+// dailyFlow 账户单日的流入、流出
+type dailyFlow struct {
+	AccountID uint
+	Day       string
+	Inflow    models.Money
+	Outflow   models.Money
+}
+
+// balanceSeries 各账户在 start 前一天的余额，以及截至各 closes 日期的余额和区间流入流出；
+// 无论账户多少，都只需两次分组查询
+func balanceSeries(userID uint, accounts []models.Account, start time.Time, closes []time.Time) (map[uint]models.Money, map[uint][]BalancePoint, error) {
+	startingBalances, err := accountBalances(userID, accounts, start)
+	if err != nil {
+		return nil, nil, err
+	}
+	series := make(map[uint][]BalancePoint, len(accounts))
+	if len(accounts) == 0 {
+		return startingBalances, series, nil
+	}
+
+	end := start
+	if len(closes) > 0 {
+		end = closes[len(closes)-1]
+	}
+	var flows []dailyFlow
+	if err := queryBalanceLegs(&flows, `account_id, DATE(transaction_date) AS day,
+		COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0) AS inflow,
+		COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0) AS outflow`, "account_id, day",
+		userID, accounts, start, end.AddDate(0, 0, 1)); err != nil {
+		return nil, nil, err
+	}
+	byAccount := make(map[uint][]dailyFlow, len(accounts))
+	for _, f := range flows {
+		byAccount[f.AccountID] = append(byAccount[f.AccountID], f)
+	}
+
+	for _, account := range accounts {
+		balance := startingBalances[account.ID]
+		accountFlows := byAccount[account.ID]
+		points := make([]BalancePoint, 0, len(closes))
+		i := 0
+		for _, closing := range closes {
+			point := BalancePoint{Date: closing.Format("2006-01-02")}
+			for ; i < len(accountFlows) && accountFlows[i].Day <= point.Date; i++ {
+				point.Inflow += accountFlows[i].Inflow
+				point.Outflow += accountFlows[i].Outflow
+			}
+			balance += point.Inflow - point.Outflow
+			point.Balance = balance
+			points = append(points, point)
+		}
+		series[account.ID] = points
+	}
+	return startingBalances, series, nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+// 批量操作类型
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// 批量结果状态
+const (
+	BatchStatusOK         = "ok"
+	BatchStatusFailed     = "failed"
+	BatchStatusRolledBack = "rolled_back" // 本身成功，但因其他操作失败被回滚
+)
+
+// BatchOperation 单个批量操作；create 的 data 同 POST /transactions，update 的 data 同 PUT /transactions/:id
+type BatchOperation struct {
+	Op   string          `json:"op" binding:"required,oneof=create update delete"`
+	ID   uint            `json:"id,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BatchTransactionRequest 批量请求，单次最多 500 个操作
+type BatchTransactionRequest struct {
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=500,dive"`
+}
+
+// BatchResult 单个操作的结果
+type BatchResult struct {
+	Index       int                 `json:"index"`
+	Op          string              `json:"op"`
+	Status      string              `json:"status"`
+	ID          uint                `json:"id,omitempty"`
+	Transaction *models.Transaction `json:"transaction,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+type BatchTransactionResponse struct {
+	Success bool          `json:"success"`
+	Results []BatchResult `json:"results"`
+}
+
+// errBatchFailed 有操作失败时用于回滚事务
+var errBatchFailed = errors.New("batch failed")
+
+// BatchTransactions 批量创建/更新/删除交易，全部操作在同一数据库事务中执行，任一失败则全部回滚
+func (h *TransactionHandler) BatchTransactions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req BatchTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results := make([]BatchResult, len(req.Operations))
+	failed := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 失败后继续执行其余操作，以便一次返回所有错误
+		for i, op := range req.Operations {
+			result, err := applyBatchOperation(tx, userID, op)
+			result.Index, result.Op = i, op.Op
+			if err != nil {
+				failed = true
+				result.Status = BatchStatusFailed
+				result.Transaction = nil
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}
+		if failed {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply batch"})
+		return
+	}
+
+	if failed {
+		for i := range results {
+			if results[i].Status == BatchStatusOK {
+				results[i].Status = BatchStatusRolledBack
+				results[i].Transaction = nil
+				if results[i].Op == BatchCreate {
+					results[i].ID = 0
+				}
+			}
+		}
+		c.JSON(http.StatusBadRequest, BatchTransactionResponse{Success: false, Results: results})
+		return
+	}
+
+	c.JSON(http.StatusOK, BatchTransactionResponse{Success: true, Results: results})
+}
+
+// applyBatchOperation 在事务中执行单个操作，校验规则与单条接口一致
+func applyBatchOperation(tx *gorm.DB, userID uint, op BatchOperation) (BatchResult, error) {
+	result := BatchResult{Status: BatchStatusOK, ID: op.ID}
+
+	switch op.Op {
+	case BatchCreate:
+		var data CreateTransactionRequest
+		if err := decodeBatchData(op.Data, &data); err != nil {
+			return result, err
+		}
+		transaction, err := createTransaction(tx, userID, data)
+		if err != nil {
+			return result, err
+		}
+		result.ID, result.Transaction = transaction.ID, transaction
+
+	case BatchUpdate:
+		if op.ID == 0 {
+			return result, badRequest("id is required for update")
+		}
+		var data UpdateTransactionRequest
+		if err := decodeBatchData(op.Data, &data); err != nil {
+			return result, err
+		}
+		if err := binding.Validator.ValidateStruct(&data); err != nil {
+			return result, badRequest(err.Error())
+		}
+		transaction, err := updateTransaction(tx, userID, op.ID, data)
+		if err != nil {
+			return result, err
+		}
+		result.Transaction = transaction
+
+	case BatchDelete:
+		if op.ID == 0 {
+			return result, badRequest("id is required for delete")
+		}
+		if err := deleteTransaction(tx, userID, op.ID); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func decodeBatchData(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return badRequest("data is required")
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest("invalid data: " + err.Error())
+	}
+	return nil
+}
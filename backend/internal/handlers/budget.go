@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+type BudgetHandler struct{}
+
+func NewBudgetHandler() *BudgetHandler {
+	return &BudgetHandler{}
+}
+
+type CreateBudgetRequest struct {
+	CategoryID uint                `json:"category_id" binding:"required"`
+	Amount     models.Money        `json:"amount" binding:"required,gt=0"`
+	Period     models.BudgetPeriod `json:"period" binding:"required,oneof=weekly monthly yearly"`
+	Rollover   bool                `json:"rollover"`
+	StartDate  string              `json:"start_date,omitempty"` // YYYY-MM-DD，默认本周期，按周期起始日对齐
+}
+
+type UpdateBudgetRequest struct {
+	Amount    models.Money        `json:"amount" binding:"omitempty,gt=0"`
+	Period    models.BudgetPeriod `json:"period" binding:"omitempty,oneof=weekly monthly yearly"`
+	Rollover  *bool               `json:"rollover"`
+	StartDate string              `json:"start_date"` // YYYY-MM-DD
+}
+
+// BudgetStatus 预算在某个周期内的执行情况
+type BudgetStatus struct {
+	BudgetID       uint                    `json:"budget_id"`
+	CategoryID     uint                    `json:"category_id"`
+	Category       string                  `json:"category"`
+	Period         models.BudgetPeriod     `json:"period"`
+	PeriodStart    string                  `json:"period_start"`
+	PeriodEnd      string                  `json:"period_end"`
+	Amount         models.Money            `json:"amount"`
+	Carryover      models.Money            `json:"carryover"` // 以往周期累计结余，超支为负
+	Available      models.Money            `json:"available"` // 本期可用 = 预算额 + 结转
+	Spent          models.Money            `json:"spent"`
+	Remaining      models.Money            `json:"remaining"`
+	PercentUsed    float64                 `json:"percent_used"`          // 已用占可用的百分比
+	PercentElapsed float64                 `json:"percent_elapsed"`       // 周期已过天数的百分比
+	Expected       models.Money            `json:"expected"`              // 按时间进度应花费的金额
+	Projected      models.Money            `json:"projected"`             // 按当前速度到周期末的花费
+	Status         string                  `json:"status"`                // on_track / over_pace / over_budget
+	Unconverted    map[string]models.Money `json:"unconverted,omitempty"` // 缺少汇率、未计入 spent 或 carryover 的原币支出
+}
+
+// GetBudgets 获取预算列表
+func (h *BudgetHandler) GetBudgets(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var budgets []models.Budget
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch budgets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, budgets)
+}
+
+// GetBudget 获取单个预算
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	budgetID := c.Param("id")
+
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// CreateBudget 创建预算；同一分类同一周期只能有一个预算
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate := time.Now()
+	if req.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		startDate = parsed
+	}
+
+	budget := models.Budget{
+		UserID:     userID,
+		CategoryID: req.CategoryID,
+		Amount:     req.Amount,
+		Period:     req.Period,
+		Rollover:   req.Rollover,
+		StartDate:  req.Period.PeriodStart(startDate),
+	}
+
+	if err := validateBudget(userID, &budget); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Create(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create budget"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, budget)
+}
+
+// UpdateBudget 更新预算
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	budgetID := c.Param("id")
+
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+
+	var req UpdateBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	if req.Amount > 0 {
+		budget.Amount = req.Amount
+	}
+	if req.Period != "" {
+		budget.Period = req.Period
+	}
+	if req.Rollover != nil {
+		budget.Rollover = *req.Rollover
+	}
+	if req.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		budget.StartDate = parsed
+	}
+	budget.StartDate = budget.Period.PeriodStart(budget.StartDate)
+
+	if err := validateBudget(userID, &budget); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Save(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// DeleteBudget 删除预算（软删除）
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	budgetID := c.Param("id")
+
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "budget deleted successfully"})
+}
+
+// GetBudgetStatus 各预算在 date（默认今天）所在周期的花费、剩余和进度
+func (h *BudgetHandler) GetBudgetStatus(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	query := database.DB.Where("user_id = ?", userID)
+	if period := c.Query("period"); period != "" {
+		query = query.Where("period = ?", period)
+	}
+	var budgets []models.Budget
+	if err := query.Order("id").Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch budgets"})
+		return
+	}
+
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute budget status"})
+		return
+	}
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := budgetStatus(userID, budget, date, cv)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute budget status"})
+			return
+		}
+		statuses = append(statuses, *status)
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// budgetStatus 计算单个预算在 date 所在周期的执行情况
+func budgetStatus(userID uint, budget models.Budget, date time.Time, cv *currencyConverter) (*BudgetStatus, error) {
+	var category models.Category
+	if err := database.DB.Unscoped().Select("name").First(&category, budget.CategoryID).Error; err != nil {
+		return nil, err
+	}
+	categoryIDs, err := categoryWithDescendants(userID, budget.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := budget.Period.PeriodStart(date)
+	next := budget.Period.NextPeriodStart(start)
+	end := next.AddDate(0, 0, -1)
+
+	spent, unconverted, err := budgetSpent(userID, categoryIDs, start, end, cv)
+	if err != nil {
+		return nil, err
+	}
+
+	// 结转：以往每个周期的 (预算额 - 花费) 累加，等于周期数 × 预算额 - 以往总花费
+	var carryover models.Money
+	if budget.Rollover && start.After(budget.StartDate) {
+		periods := 0
+		for p := budget.StartDate; p.Before(start); p = budget.Period.NextPeriodStart(p) {
+			periods++
+		}
+		previous, previousUnconverted, err := budgetSpent(userID, categoryIDs, budget.StartDate, start.AddDate(0, 0, -1), cv)
+		if err != nil {
+			return nil, err
+		}
+		carryover = budget.Amount*models.Money(periods) - previous
+		// 以往周期缺少汇率的支出同样未计入结转，一并列出
+		for currency, amount := range previousUnconverted {
+			if unconverted == nil {
+				unconverted = make(map[string]models.Money)
+			}
+			unconverted[currency] += amount
+		}
+	}
+
+	totalDays := next.Sub(start).Hours() / 24
+	elapsedDays := date.Sub(start).Hours()/24 + 1
+	if elapsedDays > totalDays {
+		elapsedDays = totalDays
+	}
+	elapsed := elapsedDays / totalDays
+
+	status := &BudgetStatus{
+		BudgetID:       budget.ID,
+		CategoryID:     budget.CategoryID,
+		Category:       category.Name,
+		Period:         budget.Period,
+		PeriodStart:    start.Format("2006-01-02"),
+		PeriodEnd:      end.Format("2006-01-02"),
+		Amount:         budget.Amount,
+		Carryover:      carryover,
+		Available:      budget.Amount + carryover,
+		Spent:          spent,
+		PercentElapsed: roundPercent(elapsed * 100),
+		Projected:      spent.MulRate(1 / elapsed),
+		Unconverted:    unconverted,
+	}
+	status.Remaining = status.Available - spent
+	if status.Available > 0 {
+		status.PercentUsed = roundPercent(spent.Float64() / status.Available.Float64() * 100)
+		status.Expected = status.Available.MulRate(elapsed)
+	}
+
+	switch {
+	case spent > status.Available:
+		status.Status = "over_budget"
+	case spent > status.Expected:
+		status.Status = "over_pace"
+	default:
+		status.Status = "on_track"
+	}
+	return status, nil
+}
+
+// budgetSpent 分类集合在 [start, end] 日期区间内的支出合计（本位币），转账和收入不计入；
+// 缺少汇率的支出按币种返回原币金额，不计入合计
+func budgetSpent(userID uint, categoryIDs []uint, start, end time.Time, cv *currencyConverter) (models.Money, map[string]models.Money, error) {
+	type group struct {
+		Currency string
+		Day      string
+		Amount   models.Money
+	}
+	var groups, splitGroups []group
+	if err := reportQuery(userID, start, end).
+		Select(currencyDayColumns("transactions")+", SUM(amount) AS amount").
+		Where("type = ? AND category_id IN ?", models.TransactionExpense, categoryIDs).
+		Where(withoutSplitsSQL).
+		Group("currency, day").
+		Scan(&groups).Error; err != nil {
+		return 0, nil, err
+	}
+	// 拆分的交易只计入属于预算分类的拆分行
+	if err := splitQuery(reportQuery(userID, start, end)).
+		Select(currencyDayColumns("transactions")+", SUM(transaction_splits.amount) AS amount").
+		Where("type = ? AND transaction_splits.category_id IN ?", models.TransactionExpense, categoryIDs).
+		Group("currency, day").
+		Scan(&splitGroups).Error; err != nil {
+		return 0, nil, err
+	}
+	groups = append(groups, splitGroups...)
+
+	var spent models.Money
+	var unconverted map[string]models.Money
+	for _, g := range groups {
+		amount, ok := cv.convertDay(g.Amount, g.Currency, g.Day)
+		if !ok {
+			if unconverted == nil {
+				unconverted = make(map[string]models.Money)
+			}
+			unconverted[g.Currency] += g.Amount
+			continue
+		}
+		spent += amount
+	}
+	return spent, unconverted, nil
+}
+
+// validateBudget 校验分类（同一用户、支出分类）和同一分类同一周期唯一
+func validateBudget(userID uint, budget *models.Budget) error {
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", budget.CategoryID, userID).First(&category).Error; err != nil {
+		return badRequest("invalid category_id")
+	}
+	if category.Kind != models.CategoryExpense {
+		return badRequest("budget category must be an expense category")
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.Budget{}).
+		Where("user_id = ? AND category_id = ? AND period = ? AND id <> ?", userID, budget.CategoryID, budget.Period, budget.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return &RequestError{Status: http.StatusConflict, Message: "budget already exists for this category and period"}
+	}
+	return nil
+}
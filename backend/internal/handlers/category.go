@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type CategoryHandler struct{}
+
+func NewCategoryHandler() *CategoryHandler {
+	return &CategoryHandler{}
+}
+
+type CreateCategoryRequest struct {
+	Name     string              `json:"name" binding:"required"`
+	Kind     models.CategoryKind `json:"kind" binding:"required,oneof=income expense"`
+	ParentID *uint               `json:"parent_id,omitempty"`
+	Icon     string              `json:"icon,omitempty"`
+	Color    string              `json:"color,omitempty"`
+}
+
+type UpdateCategoryRequest struct {
+	Name     string  `json:"name"`
+	ParentID *uint   `json:"parent_id"` // 0 表示移到顶级
+	Icon     *string `json:"icon"`
+	Color    *string `json:"color"`
+	Archived *bool   `json:"archived"`
+}
+
+type MergeCategoryRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// GetCategories 获取分类列表（支持按类型筛选，默认不含已归档分类）
+func (h *CategoryHandler) GetCategories(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := database.DB.Where("user_id = ?", userID)
+
+	// 筛选：类型
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	// 筛选：归档
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived = ?", false)
+	}
+
+	var categories []models.Category
+	if err := query.Order("kind, parent_id, name").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// GetCategory 获取单个分类
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	categoryID := c.Param("id")
+
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// CreateCategory 创建分类
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := models.Category{
+		UserID: userID,
+		Name:   strings.TrimSpace(req.Name),
+		Kind:   req.Kind,
+		Icon:   req.Icon,
+		Color:  req.Color,
+	}
+	if category.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	if req.ParentID != nil && *req.ParentID != 0 {
+		category.ParentID = req.ParentID
+	}
+
+	if err := validateCategory(userID, &category); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory 更新分类；重命名会同步到已关联交易的分类名称
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	categoryID := c.Param("id")
+
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	renamed := false
+	if name := strings.TrimSpace(req.Name); name != "" && name != category.Name {
+		category.Name = name
+		renamed = true
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		} else {
+			category.ParentID = req.ParentID
+		}
+	}
+	if req.Icon != nil {
+		category.Icon = *req.Icon
+	}
+	if req.Color != nil {
+		category.Color = *req.Color
+	}
+	if req.Archived != nil {
+		category.Archived = *req.Archived
+	}
+
+	if err := validateCategory(userID, &category); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// Select("*") 保证 parent_id 置空也能写入
+		if err := tx.Select("*").Save(&category).Error; err != nil {
+			return err
+		}
+		if renamed {
+			if err := tx.Model(&models.Transaction{}).Where("category_id = ?", category.ID).Update("category", category.Name).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.TransactionSplit{}).Where("category_id = ?", category.ID).Update("category", category.Name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory 删除分类（软删除）；有子分类或交易的分类只能归档
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	categoryID := c.Param("id")
+
+	var category models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	var children int64
+	if err := database.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category"})
+		return
+	}
+	if children > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "category has subcategories"})
+		return
+	}
+	var transactions int64
+	if err := database.DB.Model(&models.Transaction{}).Where("category_id = ?", category.ID).Count(&transactions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category"})
+		return
+	}
+	var splits int64
+	if err := database.DB.Model(&models.TransactionSplit{}).Where("category_id = ?", category.ID).Count(&splits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category"})
+		return
+	}
+	if transactions+splits > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "category is used by transactions, archive it instead"})
+		return
+	}
+
+	if err := database.DB.Delete(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted successfully"})
+}
+
+// MergeCategory 将分类合并到目标分类（如同义的“吃饭”并入“餐饮”）：交易、拆分明细、周期交易、规则和预算改挂到目标分类，
+// 子分类移到目标分类下，随后删除源分类。目标分类已有同周期预算时，源分类的该预算被删除。
+// 已对账的交易（及其拆分明细）被锁定，保留源分类，统计时仍按已删除的源分类归类。
+func (h *CategoryHandler) MergeCategory(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	categoryID := c.Param("id")
+
+	var source models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", categoryID, userID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+
+	var req MergeCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var target models.Category
+	if err := database.DB.Where("id = ? AND user_id = ?", req.TargetID, userID).First(&target).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid target_id"})
+		return
+	}
+	if target.Kind != source.Kind {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target category must be of the same kind"})
+		return
+	}
+	ids, err := categoryWithDescendants(userID, source.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge category"})
+		return
+	}
+	for _, id := range ids {
+		if id == target.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "category cannot be merged into itself or its subcategory"})
+			return
+		}
+	}
+
+	// 子分类移到目标分类下后不能与目标分类已有的子分类重名
+	var clashes int64
+	if err := database.DB.Model(&models.Category{}).
+		Where("parent_id = ? AND name IN (?)", target.ID,
+			database.DB.Model(&models.Category{}).Select("name").Where("parent_id = ?", source.ID)).
+		Count(&clashes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge category"})
+		return
+	}
+	if clashes > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "target category already has subcategories with the same names"})
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		relink := map[string]interface{}{"category_id": target.ID, "category": target.Name}
+		// 含已删除的交易，保证恢复后仍归属有效分类；已对账的交易被锁定，保留原分类
+		if err := tx.Unscoped().Model(&models.Transaction{}).
+			Where("category_id = ? AND status <> ?", source.ID, models.TransactionReconciled).
+			Updates(relink).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TransactionSplit{}).
+			Where("category_id = ?", source.ID).
+			Where("transaction_id NOT IN (?)", tx.Unscoped().Model(&models.Transaction{}).Select("id").Where("status = ?", models.TransactionReconciled)).
+			Updates(relink).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).Where("category_id = ?", source.ID).Updates(relink).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Rule{}).Where("set_category_id = ?", source.ID).Update("set_category_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		var budgets []models.Budget
+		if err := tx.Where("category_id = ?", source.ID).Find(&budgets).Error; err != nil {
+			return err
+		}
+		for _, budget := range budgets {
+			var count int64
+			if err := tx.Model(&models.Budget{}).
+				Where("category_id = ? AND period = ?", target.ID, budget.Period).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				if err := tx.Delete(&budget).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&budget).Update("category_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", source.ID).Update("parent_id", target.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&source).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to merge category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// validateCategory 校验父分类（同一用户、同一类型、不能形成环）和同级名称唯一
+func validateCategory(userID uint, category *models.Category) error {
+	if category.ParentID != nil {
+		var parent models.Category
+		if err := database.DB.Where("id = ? AND user_id = ?", *category.ParentID, userID).First(&parent).Error; err != nil {
+			return badRequest("invalid parent_id")
+		}
+		if parent.Kind != category.Kind {
+			return badRequest("parent category must be of the same kind")
+		}
+
+		// 沿父链向上查找，遇到自身说明形成环
+		if category.ID != 0 {
+			parents, err := categoryParents(userID)
+			if err != nil {
+				return err
+			}
+			for id := parent.ID; id != 0; id = parents[id] {
+				if id == category.ID {
+					return badRequest("category cannot be moved under its own subcategory")
+				}
+			}
+		}
+	}
+
+	query := database.DB.Model(&models.Category{}).
+		Where("user_id = ? AND kind = ? AND name = ? AND id <> ?", userID, category.Kind, category.Name, category.ID)
+	if category.ParentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *category.ParentID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return &RequestError{Status: http.StatusConflict, Message: "category already exists"}
+	}
+	return nil
+}
+
+// categoryParents 用户全部分类（含已删除，保证历史交易可归属）的 id -> parent_id 映射，顶级分类为 0
+func categoryParents(userID uint) (map[uint]uint, error) {
+	var categories []models.Category
+	if err := database.DB.Unscoped().Select("id", "parent_id").Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	parents := make(map[uint]uint, len(categories))
+	for _, cat := range categories {
+		if cat.ParentID != nil {
+			parents[cat.ID] = *cat.ParentID
+		} else {
+			parents[cat.ID] = 0
+		}
+	}
+	return parents, nil
+}
+
+// categoryWithDescendants 返回分类自身及全部子孙分类的 id
+func categoryWithDescendants(userID, categoryID uint) ([]uint, error) {
+	parents, err := categoryParents(userID)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for id, parent := range parents {
+		children[parent] = append(children[parent], id)
+	}
+
+	ids := []uint{categoryID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// resolveTransactionCategory 确定交易分类：优先使用 category_id，否则按名称匹配同类型、未归档的分类，不存在时自动创建顶级分类。
+// 返回分类 id 和规范化后的名称；两者都为空表示未分类。
+func resolveTransactionCategory(db *gorm.DB, userID uint, txType models.TransactionType, categoryID *uint, name string) (*uint, string, error) {
+	kind := models.CategoryKindFor(txType)
+
+	if categoryID != nil && *categoryID != 0 {
+		var category models.Category
+		if err := db.Where("id = ? AND user_id = ?", *categoryID, userID).First(&category).Error; err != nil {
+			return nil, "", badRequest("invalid category_id")
+		}
+		if category.Archived {
+			return nil, "", badRequest("category is archived")
+		}
+		if category.Kind != kind {
+			return nil, "", badRequest("category kind does not match transaction type")
+		}
+		return &category.ID, category.Name, nil
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", nil
+	}
+
+	// 同名时优先顶级分类；已归档的分类不再用于新交易
+	var category models.Category
+	err := db.Where("user_id = ? AND kind = ? AND name = ? AND archived = ?", userID, kind, name, false).
+		Order("parent_id IS NOT NULL, id").
+		First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 只有已归档的同名分类时拒绝，避免新建同名顶级分类与之冲突
+		var archived int64
+		if err := db.Model(&models.Category{}).
+			Where("user_id = ? AND kind = ? AND name = ?", userID, kind, name).
+			Count(&archived).Error; err != nil {
+			return nil, "", err
+		}
+		if archived > 0 {
+			return nil, "", badRequest("category " + name + " is archived")
+		}
+		category = models.Category{UserID: userID, Name: name, Kind: kind}
+		err = db.Create(&category).Error
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &category.ID, category.Name, nil
+}
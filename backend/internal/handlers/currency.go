@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// defaultCurrency 账户和用户本位币的默认币种
+const defaultCurrency = "CNY"
+
+// normalizeCurrency 币种代码统一为大写，只允许 3-10 位字母数字（如 CNY、USD、USDT）
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) < 3 || len(currency) > 10 {
+		return "", fmt.Errorf("invalid currency %q", currency)
+	}
+	for _, r := range currency {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return "", fmt.Errorf("invalid currency %q", currency)
+		}
+	}
+	return currency, nil
+}
+
+type ratePoint struct {
+	date time.Time
+	rate float64
+}
+
+// currencyConverter 按汇率表将各币种金额折算为用户的本位币。
+// 取折算日期当天或之前最近的一条汇率，早于第一条汇率的日期视为缺少汇率。
+// 只使用与本位币直接相关的汇率（X->本位币，或本位币->X 取倒数），找不到时记为缺失；
+// 缺失的金额由调用方按原币单独列出，不计入本位币合计。
+type currencyConverter struct {
+	base    string
+	rates   map[string][]ratePoint // 币种 -> 按日期升序的折算率（1 单位该币种 = rate 本位币）
+	missing map[string]bool
+}
+
+// loadCurrencyConverter 加载用户的本位币和全部相关汇率
+func loadCurrencyConverter(userID uint) (*currencyConverter, error) {
+	var user models.User
+	if err := database.DB.Select("id", "base_currency").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	base := user.BaseCurrency
+	if base == "" {
+		base = defaultCurrency
+	}
+
+	var rates []models.ExchangeRate
+	if err := database.DB.Where("user_id = ? AND (to_currency = ? OR from_currency = ?)", userID, base, base).
+		Find(&rates).Error; err != nil {
+		return nil, err
+	}
+
+	// 同一天同时有正反两个方向时以直接汇率为准：排在后面覆盖
+	isDirect := func(r models.ExchangeRate) bool { return r.ToCurrency == base }
+	sort.SliceStable(rates, func(i, j int) bool {
+		if !rates[i].Date.Equal(rates[j].Date) {
+			return rates[i].Date.Before(rates[j].Date)
+		}
+		return !isDirect(rates[i]) && isDirect(rates[j])
+	})
+
+	cv := &currencyConverter{base: base, rates: make(map[string][]ratePoint), missing: make(map[string]bool)}
+	for _, r := range rates {
+		currency, rate := r.FromCurrency, r.Rate
+		if !isDirect(r) {
+			currency, rate = r.ToCurrency, 1/r.Rate
+		}
+		if currency == base || r.Rate <= 0 {
+			continue
+		}
+		points := cv.rates[currency]
+		if n := len(points); n > 0 && points[n-1].date.Equal(r.Date) {
+			points[n-1].rate = rate
+		} else {
+			cv.rates[currency] = append(points, ratePoint{date: r.Date, rate: rate})
+		}
+	}
+	return cv, nil
+}
+
+// convert 将 date 当天的金额折算为本位币；缺少汇率时返回 false 并记录该币种
+func (cv *currencyConverter) convert(amount models.Money, currency string, date time.Time) (models.Money, bool) {
+	if amount == 0 || currency == cv.base {
+		return amount, true
+	}
+	points := cv.rates[currency]
+	i := sort.Search(len(points), func(i int) bool { return points[i].date.After(date) })
+	if i == 0 {
+		cv.missing[currency] = true
+		return 0, false
+	}
+	return amount.MulRate(points[i-1].rate), true
+}
+
+// convertDay 同 convert，日期为 YYYY-MM-DD 字符串（SQL DATE() 的结果）
+func (cv *currencyConverter) convertDay(amount models.Money, currency, day string) (models.Money, bool) {
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		date = time.Now()
+	}
+	return cv.convert(amount, currency, date)
+}
+
+// missingRates 折算中缺少汇率的币种
+func (cv *currencyConverter) missingRates() []string {
+	currencies := make([]string, 0, len(cv.missing))
+	for currency := range cv.missing {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// currencyDayColumns 交易所在账户的币种和交易日期，用于先按币种、日期分组再逐组折算
+func currencyDayColumns(table string) string {
+	return fmt.Sprintf("COALESCE((SELECT currency FROM accounts WHERE accounts.id = %[1]s.account_id), '%[2]s') AS currency, DATE(%[1]s.transaction_date) AS day", table, defaultCurrency)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+// 分页参数
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+var (
+	errInvalidCursor  = errors.New("invalid cursor")
+	errCursorMismatch = errors.New("cursor does not match sort order")
+)
+
+// transactionSort 交易列表排序方式；排序列均以 id 结尾，保证排序键唯一，可用于游标分页
+type transactionSort struct {
+	Name    string
+	Columns []string
+	Desc    bool
+}
+
+// transactionSorts 允许的排序字段（白名单，防止任意字符串进入 ORDER BY）
+var transactionSorts = map[string][]string{
+	"date":       {"transaction_date", "created_at", "id"},
+	"created_at": {"created_at", "id"},
+	"amount":     {"amount", "transaction_date", "created_at", "id"},
+}
+
+// defaultTransactionSort 默认按交易日期倒序
+var defaultTransactionSort = transactionSort{Name: "date", Columns: transactionSorts["date"], Desc: true}
+
+// parseTransactionSort 解析 sort（date/amount/created_at）和 order（asc/desc，默认 desc）参数
+func parseTransactionSort(sort, order string) (transactionSort, error) {
+	if sort == "" {
+		sort = defaultTransactionSort.Name
+	}
+	columns, ok := transactionSorts[sort]
+	if !ok {
+		return transactionSort{}, errors.New("invalid sort, use date, amount or created_at")
+	}
+
+	desc := true
+	switch strings.ToLower(order) {
+	case "", "desc":
+	case "asc":
+		desc = false
+	default:
+		return transactionSort{}, errors.New("invalid order, use asc or desc")
+	}
+	return transactionSort{Name: sort, Columns: columns, Desc: desc}, nil
+}
+
+// orderClause 生成 ORDER BY 子句，列名全部来自白名单
+func (s transactionSort) orderClause() string {
+	direction := " ASC"
+	if s.Desc {
+		direction = " DESC"
+	}
+	parts := make([]string, len(s.Columns))
+	for i, col := range s.Columns {
+		parts[i] = col + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// transactionCursor 交易列表游标，记录上一页最后一行的排序键
+type transactionCursor struct {
+	Sort            string       `json:"s"`
+	Desc            bool         `json:"o"`
+	TransactionDate time.Time    `json:"d"`
+	CreatedAt       time.Time    `json:"c"`
+	Amount          models.Money `json:"a"`
+	ID              uint         `json:"i"`
+}
+
+func newTransactionCursor(sort transactionSort, last models.Transaction) transactionCursor {
+	return transactionCursor{
+		Sort:            sort.Name,
+		Desc:            sort.Desc,
+		TransactionDate: last.TransactionDate,
+		CreatedAt:       last.CreatedAt,
+		Amount:          last.Amount,
+		ID:              last.ID,
+	}
+}
+
+// encode 编码为不透明的 URL 安全字符串
+func (cur transactionCursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTransactionCursor(s string) (*transactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cur transactionCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID == 0 {
+		return nil, errInvalidCursor
+	}
+	return &cur, nil
+}
+
+// applyAfter 只保留按 sort 排序时位于游标之后的行（SQLite 行值比较）
+func (cur transactionCursor) applyAfter(query *gorm.DB, sort transactionSort) (*gorm.DB, error) {
+	if cur.Sort != sort.Name || cur.Desc != sort.Desc {
+		return nil, errCursorMismatch
+	}
+
+	values := make([]interface{}, len(sort.Columns))
+	for i, col := range sort.Columns {
+		switch col {
+		case "transaction_date":
+			values[i] = cur.TransactionDate
+		case "created_at":
+			values[i] = cur.CreatedAt
+		case "amount":
+			values[i] = cur.Amount
+		case "id":
+			values[i] = cur.ID
+		}
+	}
+
+	op := ">"
+	if sort.Desc {
+		op = "<"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	return query.Where("("+strings.Join(sort.Columns, ", ")+") "+op+" ("+placeholders+")", values...), nil
+}
+
+// parsePageLimit 解析 limit 参数，缺省为 defaultPageLimit
+func parsePageLimit(raw string) (int, error) {
+	if raw == "" {
+		return defaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		return 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+	}
+	return limit, nil
+}
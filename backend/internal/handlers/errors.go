@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestError 携带 HTTP 状态码的业务错误，供 HTTP 以外的调用方（如 MCP）复用校验逻辑
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+func badRequest(message string) *RequestError {
+	return &RequestError{Status: http.StatusBadRequest, Message: message}
+}
+
+// respondError 将错误写为 JSON 响应，非 RequestError 一律按 500 处理
+func respondError(c *gin.Context, err error) {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		c.JSON(reqErr.Status, gin.H{"error": reqErr.Message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
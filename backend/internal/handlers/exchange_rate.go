@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRateFileSize 汇率 CSV 文件大小上限
+const maxRateFileSize = 5 << 20
+
+type ExchangeRateHandler struct{}
+
+func NewExchangeRateHandler() *ExchangeRateHandler {
+	return &ExchangeRateHandler{}
+}
+
+// SetExchangeRateRequest 录入汇率：1 单位 from 兑换 rate 单位 to；同一天同一币种对已有记录时覆盖
+type SetExchangeRateRequest struct {
+	Date string  `json:"date" binding:"required"` // YYYY-MM-DD
+	From string  `json:"from" binding:"required"`
+	To   string  `json:"to" binding:"required"`
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// RateImportRow 汇率导入中单行的处理结果
+type RateImportRow struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"` // created / updated / failed
+	Reason string `json:"reason,omitempty"`
+}
+
+// RateImportReport 汇率导入结果
+type RateImportReport struct {
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Failed  int             `json:"failed"`
+	Rows    []RateImportRow `json:"rows"`
+}
+
+// GetExchangeRates 获取汇率列表（支持按币种和日期筛选）
+func (h *ExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := database.DB.Where("user_id = ?", userID)
+
+	// 筛选：币种
+	if from := c.Query("from"); from != "" {
+		query = query.Where("from_currency = ?", strings.ToUpper(from))
+	}
+	if to := c.Query("to"); to != "" {
+		query = query.Where("to_currency = ?", strings.ToUpper(to))
+	}
+
+	// 筛选：日期范围
+	if raw := c.Query("start_date"); raw != "" {
+		startDate, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("date >= ?", startDate)
+	}
+	if raw := c.Query("end_date"); raw != "" {
+		endDate, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+		query = query.Where("date <= ?", endDate)
+	}
+
+	var rates []models.ExchangeRate
+	if err := query.Order("date DESC, from_currency, to_currency").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// SetExchangeRate 录入汇率
+func (h *ExchangeRateHandler) SetExchangeRate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req SetExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, created, err := saveExchangeRate(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, rate)
+}
+
+// DeleteExchangeRate 删除汇率（软删除）
+func (h *ExchangeRateHandler) DeleteExchangeRate(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	rateID := c.Param("id")
+
+	var rate models.ExchangeRate
+	if err := database.DB.Where("id = ? AND user_id = ?", rateID, userID).First(&rate).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "exchange rate not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete exchange rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "exchange rate deleted successfully"})
+}
+
+// ImportExchangeRates 从 CSV 导入汇率（multipart：file），列为 date,from,to,rate，表头可选
+func (h *ExchangeRateHandler) ImportExchangeRates(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxRateFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	report := RateImportReport{Rows: []RateImportRow{}}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read csv: " + err.Error()})
+			return
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		// 表头
+		if report.Created+report.Updated+report.Failed == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+
+		row := RateImportRow{Line: line}
+		if len(record) < 4 {
+			row.Status, row.Reason = "failed", "expected columns: date,from,to,rate"
+		} else if rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64); err != nil || rate <= 0 {
+			row.Status, row.Reason = "failed", "invalid rate"
+		} else {
+			_, created, err := saveExchangeRate(userID, SetExchangeRateRequest{
+				Date: strings.TrimSpace(record[0]),
+				From: record[1],
+				To:   record[2],
+				Rate: rate,
+			})
+			switch {
+			case err != nil:
+				row.Status, row.Reason = "failed", err.Error()
+			case created:
+				row.Status = "created"
+			default:
+				row.Status = "updated"
+			}
+		}
+
+		switch row.Status {
+		case "created":
+			report.Created++
+		case "updated":
+			report.Updated++
+		default:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// saveExchangeRate 校验并写入汇率，同一天同一币种对已存在时更新，返回是否新建
+func saveExchangeRate(userID uint, req SetExchangeRateRequest) (*models.ExchangeRate, bool, error) {
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, false, badRequest("invalid date format, use YYYY-MM-DD")
+	}
+	from, err := normalizeCurrency(req.From)
+	if err != nil {
+		return nil, false, badRequest(err.Error())
+	}
+	to, err := normalizeCurrency(req.To)
+	if err != nil {
+		return nil, false, badRequest(err.Error())
+	}
+	if from == to {
+		return nil, false, badRequest("from and to must be different currencies")
+	}
+	if req.Rate <= 0 {
+		return nil, false, badRequest("rate must be positive")
+	}
+
+	pair := database.DB.Where("user_id = ? AND from_currency = ? AND to_currency = ? AND date = ?", userID, from, to, date).
+		Session(&gorm.Session{})
+	var existing int64
+	if err := pair.Model(&models.ExchangeRate{}).Count(&existing).Error; err != nil {
+		return nil, false, err
+	}
+
+	// 唯一索引含已删除的记录，冲突时覆盖汇率并恢复该记录
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "from_currency"}, {Name: "to_currency"}, {Name: "date"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"rate":       req.Rate,
+			"updated_at": time.Now(),
+			"deleted_at": nil,
+		}),
+	}).Create(&models.ExchangeRate{UserID: userID, FromCurrency: from, ToCurrency: to, Date: date, Rate: req.Rate}).Error; err != nil {
+		return nil, false, err
+	}
+	var rate models.ExchangeRate
+	if err := pair.First(&rate).Error; err != nil {
+		return nil, false, err
+	}
+	return &rate, existing == 0, nil
+}
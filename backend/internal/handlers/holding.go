@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type HoldingHandler struct{}
+
+func NewHoldingHandler() *HoldingHandler {
+	return &HoldingHandler{}
+}
+
+type CreateHoldingRequest struct {
+	AccountID      uint             `json:"account_id" binding:"required"`
+	AssetType      models.AssetType `json:"asset_type" binding:"required"`
+	Symbol         string           `json:"symbol" binding:"required"`
+	Name           string           `json:"name,omitempty"`
+	Quantity       float64          `json:"quantity" binding:"gte=0"`
+	CostBasisTotal models.Money     `json:"cost_basis_total" binding:"gte=0"`
+	Currency       string           `json:"currency,omitempty"`
+	LastPrice      *float64         `json:"last_price,omitempty" binding:"omitempty,gte=0"`
+	MarketValue    *models.Money    `json:"market_value,omitempty" binding:"omitempty,gte=0"`
+}
+
+type UpdateHoldingRequest struct {
+	AccountID      uint             `json:"account_id"`
+	AssetType      models.AssetType `json:"asset_type"`
+	Symbol         string           `json:"symbol"`
+	Name           string           `json:"name"`
+	Quantity       *float64         `json:"quantity" binding:"omitempty,gte=0"`
+	CostBasisTotal *models.Money    `json:"cost_basis_total" binding:"omitempty,gte=0"`
+	Currency       string           `json:"currency"`
+	LastPrice      *float64         `json:"last_price" binding:"omitempty,gte=0"`
+	MarketValue    *models.Money    `json:"market_value" binding:"omitempty,gte=0"`
+}
+
+// GetHoldings 获取持仓列表（支持按账户/资产类型筛选）
+func (h *HoldingHandler) GetHoldings(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := database.DB.Where("user_id = ?", userID)
+
+	// 筛选：账户
+	if accountID := c.Query("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+
+	// 筛选：资产类型
+	if assetType := c.Query("asset_type"); assetType != "" {
+		query = query.Where("asset_type = ?", assetType)
+	}
+
+	var holdings []models.Holding
+	if err := query.Order("account_id, asset_type, symbol").Find(&holdings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch holdings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holdings)
+}
+
+// GetHolding 获取单个持仓
+func (h *HoldingHandler) GetHolding(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	holdingID := c.Param("id")
+
+	var holding models.Holding
+	if err := database.DB.Where("id = ? AND user_id = ?", holdingID, userID).First(&holding).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "holding not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holding)
+}
+
+// CreateHolding 创建持仓
+func (h *HoldingHandler) CreateHolding(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateHoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !isValidAssetType(req.AssetType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid asset_type"})
+		return
+	}
+	// binding:"required" 不会拒绝纯空白的代码
+	symbol := strings.TrimSpace(req.Symbol)
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol is required"})
+		return
+	}
+
+	// 验证账户是否属于当前用户
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+
+	holding := models.Holding{
+		UserID:         userID,
+		AccountID:      req.AccountID,
+		AssetType:      req.AssetType,
+		Symbol:         symbol,
+		Name:           req.Name,
+		Quantity:       req.Quantity,
+		CostBasisTotal: req.CostBasisTotal,
+		Currency:       req.Currency,
+		MarketValue:    req.MarketValue,
+	}
+
+	// 币种默认跟随账户
+	if holding.Currency == "" {
+		holding.Currency = account.Currency
+	}
+	currency, err := normalizeCurrency(holding.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	holding.Currency = currency
+	if req.LastPrice != nil {
+		setHoldingPrice(&holding, *req.LastPrice, req.MarketValue == nil)
+	}
+
+	// 同一账户下同类资产的代码唯一；软删除的旧记录直接复用，避免唯一索引冲突
+	var existing models.Holding
+	err = database.DB.Unscoped().
+		Where("account_id = ? AND asset_type = ? AND symbol = ?", holding.AccountID, holding.AssetType, holding.Symbol).
+		First(&existing).Error
+	if err == nil {
+		if !existing.DeletedAt.Valid {
+			c.JSON(http.StatusConflict, gin.H{"error": "holding already exists for this account and symbol"})
+			return
+		}
+		holding.ID = existing.ID
+		holding.CreatedAt = existing.CreatedAt
+		if err := database.DB.Unscoped().Save(&holding).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create holding"})
+			return
+		}
+		c.JSON(http.StatusCreated, holding)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create holding"})
+		return
+	}
+
+	if err := database.DB.Create(&holding).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create holding"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, holding)
+}
+
+// UpdateHolding 更新持仓
+func (h *HoldingHandler) UpdateHolding(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	holdingID := c.Param("id")
+
+	var holding models.Holding
+	if err := database.DB.Where("id = ? AND user_id = ?", holdingID, userID).First(&holding).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "holding not found"})
+		return
+	}
+
+	var req UpdateHoldingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	if req.AccountID != 0 {
+		// 验证账户
+		var account models.Account
+		if err := database.DB.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+			return
+		}
+		holding.AccountID = req.AccountID
+	}
+	if req.AssetType != "" {
+		if !isValidAssetType(req.AssetType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid asset_type"})
+			return
+		}
+		holding.AssetType = req.AssetType
+	}
+	if req.Symbol != "" {
+		symbol := strings.TrimSpace(req.Symbol)
+		if symbol == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "symbol must not be blank"})
+			return
+		}
+		holding.Symbol = symbol
+	}
+	if req.Name != "" {
+		holding.Name = req.Name
+	}
+	if req.Quantity != nil {
+		holding.Quantity = *req.Quantity
+	}
+	if req.CostBasisTotal != nil {
+		holding.CostBasisTotal = *req.CostBasisTotal
+	}
+	if req.Currency != "" {
+		currency, err := normalizeCurrency(req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		holding.Currency = currency
+	}
+	if req.MarketValue != nil {
+		holding.MarketValue = req.MarketValue
+	}
+	if req.LastPrice != nil {
+		setHoldingPrice(&holding, *req.LastPrice, req.MarketValue == nil)
+	} else if req.Quantity != nil && req.MarketValue == nil && holding.LastPrice != nil {
+		// 数量变化时按最新价格重算市值
+		marketValue := models.MoneyFromFloat(holding.Quantity * *holding.LastPrice)
+		holding.MarketValue = &marketValue
+	}
+
+	// 检查修改后是否与其他持仓冲突
+	var count int64
+	if err := database.DB.Model(&models.Holding{}).
+		Where("id <> ? AND account_id = ? AND asset_type = ? AND symbol = ?", holding.ID, holding.AccountID, holding.AssetType, holding.Symbol).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update holding"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "holding already exists for this account and symbol"})
+		return
+	}
+
+	// 清理占用同一唯一键的软删除记录
+	if err := database.DB.Unscoped().
+		Where("id <> ? AND account_id = ? AND asset_type = ? AND symbol = ? AND deleted_at IS NOT NULL", holding.ID, holding.AccountID, holding.AssetType, holding.Symbol).
+		Delete(&models.Holding{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update holding"})
+		return
+	}
+
+	if err := database.DB.Save(&holding).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update holding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, holding)
+}
+
+// DeleteHolding 删除持仓（软删除）
+func (h *HoldingHandler) DeleteHolding(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	holdingID := c.Param("id")
+
+	var holding models.Holding
+	if err := database.DB.Where("id = ? AND user_id = ?", holdingID, userID).First(&holding).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "holding not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&holding).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete holding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "holding deleted successfully"})
+}
+
+// setHoldingPrice 更新最新价格，必要时按数量重算市值
+func setHoldingPrice(holding *models.Holding, price float64, recalcMarketValue bool) {
+	now := time.Now()
+	holding.LastPrice = &price
+	holding.LastPriceAt = &now
+	if recalcMarketValue {
+		marketValue := models.MoneyFromFloat(holding.Quantity * price)
+		holding.MarketValue = &marketValue
+	}
+}
+
+func isValidAssetType(t models.AssetType) bool {
+	switch t {
+	case models.AssetTypeStock, models.AssetTypeFund, models.AssetTypeCrypto,
+		models.AssetTypeBond, models.AssetTypeCash, models.AssetTypeOther:
+		return true
+	}
+	return false
+}
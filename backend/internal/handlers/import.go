@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/importer"
+	"github.com/jasxu/fi_system/internal/models"
+	"github.com/jasxu/fi_system/internal/rules"
+	"gorm.io/gorm"
+)
+
+// maxImportFileSize 单个账单文件大小上限
+const maxImportFileSize = 20 << 20
+
+// errImportRowRolledBack 未成功导入的行回滚其事务，撤销自动创建的分类和标签
+var errImportRowRolledBack = errors.New("import row rolled back")
+
+// ImportTransactions 导入第三方账单（multipart：file、account_id、source、transfer_account_id）
+func (h *TransactionHandler) ImportTransactions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	accountID, err := strconv.ParseUint(c.PostForm("account_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account_id is required"})
+		return
+	}
+
+	// 验证账户是否属于当前用户
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+
+	// 不计收支的行按转账导入，需要指定对方账户
+	var transferAccountID *uint
+	if raw := c.PostForm("transfer_account_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transfer_account_id"})
+			return
+		}
+		var toAccount models.Account
+		if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&toAccount).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transfer_account_id"})
+			return
+		}
+		// 账单只有单边金额，无法确定跨币种转账的入账金额
+		if toAccount.Currency != account.Currency {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transfer_account_id must use the same currency as account_id"})
+			return
+		}
+		toID := uint(id)
+		transferAccountID = &toID
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file too large"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	source := importer.Source(c.DefaultPostForm("source", string(importer.SourceAlipay)))
+
+	var records []importer.Record
+	switch source {
+	case importer.SourceAlipay:
+		records, err = importer.ParseAlipayCSV(data)
+	case importer.SourceWechat:
+		if account.Type != models.AccountTypeWechat {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wechat bills must be imported into a wechat account"})
+			return
+		}
+		records, err = importer.ParseWechat(data)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported source"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ruleSet, err := loadRules(database.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load rules"})
+		return
+	}
+
+	report := importer.Report{Source: source, Rows: []importer.RowResult{}}
+	for _, record := range records {
+		// 每行在独立事务中导入，失败的行不会留下自动创建的分类和标签
+		var result importer.RowResult
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			result = importRecord(tx, userID, uint(accountID), transferAccountID, ruleSet, record)
+			if result.Status != importer.StatusCreated {
+				return errImportRowRolledBack
+			}
+			return nil
+		})
+		if err != nil && result.Status == importer.StatusCreated {
+			result = importer.RowResult{Line: record.Line, Status: importer.StatusFailed, Reason: "failed to create transaction"}
+		}
+		report.Add(result)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importRecord 在 db（单行的事务）中将一行账单写入交易表，校验和对账规则与手工创建交易一致
+func importRecord(db *gorm.DB, userID, accountID uint, transferAccountID *uint, ruleSet []*rules.Rule, record importer.Record) importer.RowResult {
+	result := importer.RowResult{Line: record.Line}
+
+	if record.Err != nil {
+		result.Status = importer.StatusFailed
+		result.Reason = record.Err.Error()
+		return result
+	}
+	if record.SkipReason != "" {
+		result.Status = importer.StatusSkipped
+		result.Reason = record.SkipReason
+		return result
+	}
+
+	req := CreateTransactionRequest{
+		AccountID:       accountID,
+		Type:            record.Type,
+		Amount:          record.Amount,
+		Category:        record.Category,
+		Merchant:        record.Merchant,
+		Description:     record.Description,
+		TransactionDate: record.Time.Format("2006-01-02"),
+		externalID:      record.ExternalID,
+		rulesApplied:    true,
+	}
+
+	if req.Type == models.TransactionTransfer {
+		if transferAccountID == nil {
+			result.Status = importer.StatusSkipped
+			result.Reason = "transfer requires transfer_account_id"
+			return result
+		}
+		if *transferAccountID == accountID {
+			result.Status = importer.StatusSkipped
+			result.Reason = "transfer to the same account"
+			return result
+		}
+		if record.Inbound {
+			req.AccountID = *transferAccountID
+			req.ToAccountID = &accountID
+		} else {
+			req.ToAccountID = transferAccountID
+		}
+	}
+
+	// 自动分类规则优先于账单自带的分类；去重前执行，保证商户名规范化后仍能识别重复
+	applyRulesToRequest(ruleSet, &req, true)
+
+	// 去重：优先按第三方单号，没有单号时按账户、日期、金额、类型和商户
+	query := db.Model(&models.Transaction{}).Where("user_id = ?", userID)
+	if req.externalID != "" {
+		query = query.Where("external_id = ?", req.externalID)
+	} else {
+		date := time.Date(record.Time.Year(), record.Time.Month(), record.Time.Day(), 0, 0, 0, 0, time.UTC)
+		query = query.Where("account_id = ? AND transaction_date = ? AND amount = ? AND type = ? AND merchant = ?",
+			req.AccountID, date, req.Amount, req.Type, req.Merchant)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		result.Status = importer.StatusFailed
+		result.Reason = "failed to check duplicates"
+		return result
+	}
+	if count > 0 {
+		result.Status = importer.StatusSkipped
+		result.Reason = "duplicate transaction"
+		return result
+	}
+
+	transaction, err := createTransaction(db, userID, req)
+	if err != nil {
+		result.Status = importer.StatusFailed
+		result.Reason = "failed to create transaction"
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) {
+			result.Reason = reqErr.Message
+		}
+		return result
+	}
+
+	result.Status = importer.StatusCreated
+	result.TransactionID = transaction.ID
+	return result
+}
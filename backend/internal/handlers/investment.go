@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/investment"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type InvestmentHandler struct{}
+
+func NewInvestmentHandler() *InvestmentHandler {
+	return &InvestmentHandler{}
+}
+
+type UpdateInvestmentSettingsRequest struct {
+	MonthlyIncome           *models.Money              `json:"monthly_income" binding:"omitempty,gte=0"`
+	MonthlyExpense          *models.Money              `json:"monthly_expense" binding:"omitempty,gte=0"`
+	CashReserveTarget       *models.Money              `json:"cash_reserve_target" binding:"omitempty,gte=0"`
+	BufferReserveTarget     *models.Money              `json:"buffer_reserve_target" binding:"omitempty,gte=0"`
+	BaseInvestmentAmount    *models.Money              `json:"base_investment_amount" binding:"omitempty,gte=0"`
+	BaseInvestmentFrequency models.InvestmentFrequency `json:"base_investment_frequency"`
+	BoostInvestmentAmount   *models.Money              `json:"boost_investment_amount" binding:"omitempty,gte=0"`
+	AssetAllocation         models.AssetAllocation     `json:"asset_allocation"`
+}
+
+// GetSettings 获取定投配置
+func (h *InvestmentHandler) GetSettings(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var settings models.InvestmentSettings
+	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "investment settings not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateSettings 更新定投配置（不存在时创建）
+func (h *InvestmentHandler) UpdateSettings(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req UpdateInvestmentSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var settings models.InvestmentSettings
+	err := database.DB.Where("user_id = ?", userID).First(&settings).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch investment settings"})
+		return
+	}
+	if settings.ID == 0 {
+		settings = models.InvestmentSettings{
+			UserID:                  userID,
+			BaseInvestmentFrequency: models.FrequencyMonthly,
+			AssetAllocation:         models.AssetAllocation{},
+		}
+	}
+
+	// 更新字段
+	if req.MonthlyIncome != nil {
+		settings.MonthlyIncome = *req.MonthlyIncome
+	}
+	if req.MonthlyExpense != nil {
+		settings.MonthlyExpense = *req.MonthlyExpense
+	}
+	if req.CashReserveTarget != nil {
+		settings.CashReserveTarget = *req.CashReserveTarget
+	}
+	if req.BufferReserveTarget != nil {
+		settings.BufferReserveTarget = *req.BufferReserveTarget
+	}
+	if req.BaseInvestmentAmount != nil {
+		settings.BaseInvestmentAmount = *req.BaseInvestmentAmount
+	}
+	if req.BaseInvestmentFrequency != "" {
+		if req.BaseInvestmentFrequency != models.FrequencyWeekly && req.BaseInvestmentFrequency != models.FrequencyMonthly {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "base_investment_frequency must be weekly or monthly"})
+			return
+		}
+		settings.BaseInvestmentFrequency = req.BaseInvestmentFrequency
+	}
+	if req.BoostInvestmentAmount != nil {
+		settings.BoostInvestmentAmount = *req.BoostInvestmentAmount
+	}
+	if req.AssetAllocation != nil {
+		if err := validateAllocation(req.AssetAllocation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings.AssetAllocation = req.AssetAllocation
+	}
+
+	if err := database.DB.Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save investment settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// GetStage 根据高/中流动性账户余额判定当前定投阶段
+func (h *InvestmentHandler) GetStage(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	_, stage, err := currentInvestmentStage(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stage)
+}
+
+// GetActions 获取行动指令列表，本周期尚未生成时先生成（regenerate=true 重新生成未执行的指令）
+func (h *InvestmentHandler) GetActions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	settings, stage, err := currentInvestmentStage(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := ensureCurrentActions(userID, settings, stage, c.Query("regenerate") == "true"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate investment actions"})
+		return
+	}
+
+	query := database.DB.Where("user_id = ?", userID)
+
+	// 筛选：执行状态
+	if executed := c.Query("executed"); executed != "" {
+		query = query.Where("executed = ?", executed == "true")
+	}
+
+	var actions []models.InvestmentAction
+	if err := query.Order("action_date DESC, id").Find(&actions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch investment actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, actions)
+}
+
+// ExecuteAction 将行动指令标记为已执行
+func (h *InvestmentHandler) ExecuteAction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	actionID := c.Param("id")
+
+	var action models.InvestmentAction
+	if err := database.DB.Where("id = ? AND user_id = ?", actionID, userID).First(&action).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "investment action not found"})
+		return
+	}
+	if action.Executed {
+		c.JSON(http.StatusConflict, gin.H{"error": "investment action already executed"})
+		return
+	}
+
+	now := time.Now()
+	action.Executed = true
+	action.ExecutedAt = &now
+	if err := database.DB.Save(&action).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update investment action"})
+		return
+	}
+
+	c.JSON(http.StatusOK, action)
+}
+
+// ensureCurrentActions 保证当前定投周期已有行动指令；regenerate 时替换未执行的指令
+func ensureCurrentActions(userID uint, settings *models.InvestmentSettings, stage investment.StageResult, regenerate bool) error {
+	var user models.User
+	if err := database.DB.Select("id", "base_currency").First(&user, userID).Error; err != nil {
+		return err
+	}
+	currency := user.BaseCurrency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+
+	generated := investment.Generate(investment.Input{
+		Date:        time.Now(),
+		Currency:    currency,
+		Stage:       stage,
+		BaseAmount:  settings.BaseInvestmentAmount,
+		Frequency:   settings.BaseInvestmentFrequency,
+		BoostAmount: settings.BoostInvestmentAmount,
+		Allocation:  settings.AssetAllocation,
+	})
+	periodStart := investment.PeriodStart(time.Now(), settings.BaseInvestmentFrequency)
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if regenerate {
+			if err := tx.Where("user_id = ? AND action_date = ? AND executed = ?", userID, periodStart, false).
+				Delete(&models.InvestmentAction{}).Error; err != nil {
+				return err
+			}
+		} else {
+			var count int64
+			if err := tx.Model(&models.InvestmentAction{}).
+				Where("user_id = ? AND action_date = ?", userID, periodStart).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+		}
+
+		for _, a := range generated {
+			action := models.InvestmentAction{
+				UserID:      userID,
+				ActionDate:  a.Date,
+				Stage:       int(a.Stage),
+				ActionType:  string(a.Type),
+				Instruction: a.Instruction,
+				Amount:      a.Amount,
+			}
+			if len(a.Breakdown) > 0 {
+				action.Breakdown = models.MoneyBreakdown{}
+				for _, b := range a.Breakdown {
+					action.Breakdown[b.Asset] = b.Amount
+				}
+			}
+			if err := tx.Create(&action).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// currentInvestmentStage 读取配置并汇总现金桶余额（本位币）：活钱桶 = 高流动性账户，缓冲桶 = 中流动性账户
+func currentInvestmentStage(userID uint) (*models.InvestmentSettings, investment.StageResult, error) {
+	var settings models.InvestmentSettings
+	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, investment.StageResult{}, badRequest("investment settings not configured")
+		}
+		return nil, investment.StageResult{}, err
+	}
+
+	accounts, err := ListAccountsWithBalance(userID)
+	if err != nil {
+		return nil, investment.StageResult{}, err
+	}
+
+	// 不同币种的账户按本位币合计
+	var cashBalance, bufferBalance models.Money
+	for _, acc := range accounts {
+		if acc.LiquidityLevel != models.LiquidityHigh && acc.LiquidityLevel != models.LiquidityMedium {
+			continue
+		}
+		if acc.BaseBalance == nil {
+			return nil, investment.StageResult{}, badRequest("missing exchange rate for " + acc.Currency + "/" + acc.BaseCurrency)
+		}
+		if acc.LiquidityLevel == models.LiquidityHigh {
+			cashBalance += *acc.BaseBalance
+		} else {
+			bufferBalance += *acc.BaseBalance
+		}
+	}
+
+	cashTarget, bufferTarget := investment.Targets(settings.MonthlyExpense, settings.CashReserveTarget, settings.BufferReserveTarget)
+	return &settings, investment.DetermineStage(cashBalance, bufferBalance, cashTarget, bufferTarget), nil
+}
+
+// validateAllocation 资产配置比例必须非负且合计为 1
+func validateAllocation(allocation models.AssetAllocation) error {
+	if len(allocation) == 0 {
+		return nil
+	}
+	var total float64
+	for name, weight := range allocation {
+		if name == "" || weight < 0 {
+			return errors.New("invalid asset_allocation entry")
+		}
+		total += weight
+	}
+	if math.Abs(total-1) > 0.0001 {
+		return errors.New("asset_allocation weights must sum to 1")
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type ReconciliationHandler struct{}
+
+func NewReconciliationHandler() *ReconciliationHandler {
+	return &ReconciliationHandler{}
+}
+
+type CreateReconciliationRequest struct {
+	AccountID        uint         `json:"account_id" binding:"required"`
+	StatementDate    string       `json:"statement_date" binding:"required"` // YYYY-MM-DD
+	StatementBalance models.Money `json:"statement_balance"`
+}
+
+// ReconciliationCandidate 截至账单日尚未对账的交易，signed_amount 为对该账户余额的影响（流入为正）
+type ReconciliationCandidate struct {
+	models.Transaction
+	SignedAmount models.Money `json:"signed_amount"`
+}
+
+// ReconciliationReport 账单余额与账面余额的比对结果
+type ReconciliationReport struct {
+	models.Reconciliation
+	Currency          string                    `json:"currency"`
+	ComputedBalance   models.Money              `json:"computed_balance"`   // 截至账单日全部交易计算的余额
+	ClearedBalance    models.Money              `json:"cleared_balance"`    // 只计已核对和已对账交易的余额
+	Difference        models.Money              `json:"difference"`         // 账单余额 - 账面余额
+	ClearedDifference models.Money              `json:"cleared_difference"` // 账单余额 - 已核对余额，为 0 时才能完成对账
+	Candidates        []ReconciliationCandidate `json:"candidates"`
+}
+
+// GetReconciliations 获取对账记录列表（支持按账户筛选）
+func (h *ReconciliationHandler) GetReconciliations(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := database.DB.Where("user_id = ?", userID)
+
+	// 筛选：账户
+	if accountID := c.Query("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+
+	var reconciliations []models.Reconciliation
+	if err := query.Order("statement_date DESC, id DESC").Find(&reconciliations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch reconciliations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reconciliations)
+}
+
+// GetReconciliation 获取对账记录及账单余额与账面余额的比对
+func (h *ReconciliationHandler) GetReconciliation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	reconciliation, err := findReconciliation(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := compareReconciliation(reconciliation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// CreateReconciliation 录入账单余额，返回比对结果
+func (h *ReconciliationHandler) CreateReconciliation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+	statementDate, err := time.Parse("2006-01-02", req.StatementDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid statement_date format, use YYYY-MM-DD"})
+		return
+	}
+
+	reconciliation := models.Reconciliation{
+		UserID:           userID,
+		AccountID:        account.ID,
+		StatementDate:    statementDate,
+		StatementBalance: req.StatementBalance,
+		Status:           models.ReconciliationOpen,
+	}
+	if err := database.DB.Create(&reconciliation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reconciliation"})
+		return
+	}
+	reconciliation.Account = account
+
+	report, err := compareReconciliation(&reconciliation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare balances"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
+
+// CompleteReconciliation 完成对账：已核对余额须与账单余额一致，截至账单日的已核对交易被锁定为 reconciled
+func (h *ReconciliationHandler) CompleteReconciliation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	reconciliation, err := findReconciliation(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if reconciliation.Status == models.ReconciliationCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "reconciliation already completed"})
+		return
+	}
+
+	report, err := compareReconciliation(reconciliation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare balances"})
+		return
+	}
+	if report.ClearedDifference != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cleared balance does not match statement balance"})
+		return
+	}
+
+	now := time.Now()
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := reconcilableTransactions(tx, reconciliation).
+			Where("status = ?", models.TransactionCleared).
+			Updates(map[string]interface{}{"status": models.TransactionReconciled, "reconciliation_id": reconciliation.ID}).Error; err != nil {
+			return err
+		}
+		reconciliation.Status = models.ReconciliationCompleted
+		reconciliation.CompletedAt = &now
+		return tx.Model(reconciliation).Select("status", "completed_at").Updates(reconciliation).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to complete reconciliation"})
+		return
+	}
+
+	report, err = compareReconciliation(reconciliation)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compare balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// DeleteReconciliation 删除对账记录（软删除），已完成的对账会解锁其交易并恢复为 cleared；
+// 已完成的对账只能从该账户最近的一次开始撤销，否则之后的对账结果将失去依据
+func (h *ReconciliationHandler) DeleteReconciliation(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	reconciliation, err := findReconciliation(userID, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if reconciliation.Status == models.ReconciliationCompleted {
+		var later int64
+		if err := database.DB.Model(&models.Reconciliation{}).
+			Where("user_id = ? AND account_id = ? AND status = ? AND id <> ?", userID, reconciliation.AccountID, models.ReconciliationCompleted, reconciliation.ID).
+			Where("statement_date > ? OR (statement_date = ? AND id > ?)", reconciliation.StatementDate, reconciliation.StatementDate, reconciliation.ID).
+			Count(&later).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reconciliation"})
+			return
+		}
+		if later > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "only the latest completed reconciliation of an account can be deleted"})
+			return
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transaction{}).
+			Where("user_id = ? AND reconciliation_id = ?", userID, reconciliation.ID).
+			Updates(map[string]interface{}{"status": models.TransactionCleared, "reconciliation_id": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(reconciliation).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reconciliation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reconciliation deleted successfully"})
+}
+
+// errReconciledPeriod 交易日期不晚于账户已完成对账的账单日，写入会改变已对账的余额
+var errReconciledPeriod = &RequestError{Status: http.StatusConflict, Message: "transaction date falls within a completed reconciliation"}
+
+// checkReconciledPeriod 交易所涉账户在交易日当天或之后有已完成的对账时返回 errReconciledPeriod
+func checkReconciledPeriod(db *gorm.DB, transaction *models.Transaction) error {
+	accountIDs := []uint{transaction.AccountID}
+	if transaction.ToAccountID != nil {
+		accountIDs = append(accountIDs, *transaction.ToAccountID)
+	}
+	var count int64
+	if err := db.Model(&models.Reconciliation{}).
+		Where("user_id = ? AND account_id IN ? AND status = ? AND statement_date >= ?",
+			transaction.UserID, accountIDs, models.ReconciliationCompleted, transaction.TransactionDate).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errReconciledPeriod
+	}
+	return nil
+}
+
+// findReconciliation 查找用户的对账记录及其账户
+func findReconciliation(userID uint, rawID string) (*models.Reconciliation, error) {
+	id, err := strconv.ParseUint(rawID, 10, 64)
+	if err != nil {
+		return nil, &RequestError{Status: http.StatusNotFound, Message: "reconciliation not found"}
+	}
+	var reconciliation models.Reconciliation
+	if err := database.DB.Joins("Account").
+		Where("reconciliations.id = ? AND reconciliations.user_id = ?", id, userID).
+		First(&reconciliation).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusNotFound, Message: "reconciliation not found"}
+	}
+	if reconciliation.Account.ID == 0 {
+		return nil, badRequest("account of this reconciliation has been deleted")
+	}
+	return &reconciliation, nil
+}
+
+// reconcilableTransactions 截至账单日计入该账户余额的交易（期初日期之前的交易已含在期初余额中）
+func reconcilableTransactions(db *gorm.DB, reconciliation *models.Reconciliation) *gorm.DB {
+	account := reconciliation.Account
+	query := db.Model(&models.Transaction{}).
+		Where("user_id = ? AND (account_id = ? OR to_account_id = ?)", reconciliation.UserID, account.ID, account.ID).
+		Where("transaction_date < ?", reconciliation.StatementDate.AddDate(0, 0, 1))
+	if account.OpeningBalanceDate != nil {
+		query = query.Where("transaction_date >= ?", *account.OpeningBalanceDate)
+	}
+	return query
+}
+
+// compareReconciliation 计算账单日的账面余额、已核对余额，并列出尚未对账的交易
+func compareReconciliation(reconciliation *models.Reconciliation) (*ReconciliationReport, error) {
+	account := reconciliation.Account
+
+	balances, err := accountBalances(reconciliation.UserID, []models.Account{account}, reconciliation.StatementDate.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	computed := balances[account.ID]
+
+	var transactions []models.Transaction
+	if err := reconcilableTransactions(database.DB, reconciliation).
+		Where("status <> ?", models.TransactionReconciled).
+		Order("transaction_date, id").
+		Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+
+	// 已核对余额 = 账面余额 - 未核对交易的影响
+	cleared := computed
+	candidates := make([]ReconciliationCandidate, 0, len(transactions))
+	for _, t := range transactions {
+		signed := signedAmount(t, account.ID)
+		if t.Status != models.TransactionCleared {
+			cleared -= signed
+		}
+		candidates = append(candidates, ReconciliationCandidate{Transaction: t, SignedAmount: signed})
+	}
+
+	return &ReconciliationReport{
+		Reconciliation:    *reconciliation,
+		Currency:          account.Currency,
+		ComputedBalance:   computed,
+		ClearedBalance:    cleared,
+		Difference:        reconciliation.StatementBalance - computed,
+		ClearedDifference: reconciliation.StatementBalance - cleared,
+		Candidates:        candidates,
+	}, nil
+}
+
+// signedAmount 交易对账户余额的影响，与 balanceLegsSQL 口径一致
+func signedAmount(t models.Transaction, accountID uint) models.Money {
+	if t.AccountID == accountID {
+		if t.Type == models.TransactionIncome {
+			return t.Amount
+		}
+		return -t.Amount
+	}
+	if t.Type != models.TransactionTransfer {
+		return 0
+	}
+	if t.ToAmount != nil {
+		return *t.ToAmount
+	}
+	return t.Amount
+}
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"github.com/jasxu/fi_system/internal/recurrence"
+	"gorm.io/gorm"
+)
+
+// maxRecurringCatchUp 单次调度中每个模板最多补齐的笔数，防止错误的排程一次生成过多交易
+const maxRecurringCatchUp = 1000
+
+type RecurringHandler struct{}
+
+func NewRecurringHandler() *RecurringHandler {
+	return &RecurringHandler{}
+}
+
+type CreateRecurringRequest struct {
+	Name        string                 `json:"name,omitempty"`
+	AccountID   uint                   `json:"account_id" binding:"required"`
+	ToAccountID *uint                  `json:"to_account_id,omitempty"`
+	Type        models.TransactionType `json:"type" binding:"required"`
+	Amount      models.Money           `json:"amount" binding:"required,gt=0"`
+	ToAmount    *models.Money          `json:"to_amount,omitempty" binding:"omitempty,gt=0"`
+	CategoryID  *uint                  `json:"category_id,omitempty"`
+	Category    string                 `json:"category,omitempty"`
+	Merchant    string                 `json:"merchant,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	RRule       string                 `json:"rrule" binding:"required"`      // 如 FREQ=MONTHLY;BYMONTHDAY=5
+	StartDate   string                 `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate     string                 `json:"end_date,omitempty"`            // YYYY-MM-DD
+}
+
+type UpdateRecurringRequest struct {
+	Name        *string                `json:"name"`
+	AccountID   uint                   `json:"account_id"`
+	ToAccountID *uint                  `json:"to_account_id"`
+	Type        models.TransactionType `json:"type"`
+	Amount      models.Money           `json:"amount" binding:"omitempty,gt=0"`
+	ToAmount    *models.Money          `json:"to_amount" binding:"omitempty,gt=0"`
+	CategoryID  *uint                  `json:"category_id"` // 0 表示清除分类
+	Category    string                 `json:"category"`
+	Merchant    *string                `json:"merchant"`
+	Description *string                `json:"description"`
+	Tags        *[]string              `json:"tags"`
+	RRule       string                 `json:"rrule"`
+	StartDate   string                 `json:"start_date"` // YYYY-MM-DD
+	EndDate     *string                `json:"end_date"`   // YYYY-MM-DD，空字符串表示不结束
+	Active      *bool                  `json:"active"`     // 暂停后恢复不补齐暂停期间的日期
+}
+
+// GetRecurringTransactions 获取周期交易列表
+func (h *RecurringHandler) GetRecurringTransactions(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var list []models.RecurringTransaction
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch recurring transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetRecurringTransaction 获取单个周期交易
+func (h *RecurringHandler) GetRecurringTransaction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	recurringID := c.Param("id")
+
+	var recurring models.RecurringTransaction
+	if err := database.DB.Where("id = ? AND user_id = ?", recurringID, userID).First(&recurring).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurring transaction not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, recurring)
+}
+
+// CreateRecurringTransaction 创建周期交易，开始日期已过的发生日期会在下次调度时补齐
+func (h *RecurringHandler) CreateRecurringTransaction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateRecurringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+		return
+	}
+	var endDate *time.Time
+	if req.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+		endDate = &parsed
+	}
+
+	recurring := models.RecurringTransaction{
+		UserID:      userID,
+		Name:        strings.TrimSpace(req.Name),
+		AccountID:   req.AccountID,
+		ToAccountID: req.ToAccountID,
+		Type:        req.Type,
+		Amount:      req.Amount,
+		ToAmount:    req.ToAmount,
+		Merchant:    req.Merchant,
+		Description: req.Description,
+		Tags:        models.StringList(req.Tags),
+		RRule:       strings.ToUpper(strings.TrimSpace(req.RRule)),
+		StartDate:   startDate,
+		EndDate:     endDate,
+		Active:      true,
+	}
+
+	if err := validateRecurring(userID, &recurring); err != nil {
+		respondError(c, err)
+		return
+	}
+	recurring.CategoryID, recurring.Category, err = resolveTransactionCategory(database.DB, userID, recurring.Type, req.CategoryID, req.Category)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := scheduleNextRun(&recurring, time.Time{}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Create(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create recurring transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, recurring)
+}
+
+// UpdateRecurringTransaction 更新周期交易；已生成的交易不受影响
+func (h *RecurringHandler) UpdateRecurringTransaction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	recurringID := c.Param("id")
+
+	var recurring models.RecurringTransaction
+	if err := database.DB.Where("id = ? AND user_id = ?", recurringID, userID).First(&recurring).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurring transaction not found"})
+		return
+	}
+
+	var req UpdateRecurringRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	if req.Name != nil {
+		recurring.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.AccountID != 0 {
+		recurring.AccountID = req.AccountID
+	}
+	if req.ToAccountID != nil {
+		recurring.ToAccountID = req.ToAccountID
+	}
+	if req.Type != "" {
+		recurring.Type = req.Type
+		if req.Type != models.TransactionTransfer {
+			recurring.ToAccountID = nil
+		}
+	}
+	if req.Amount > 0 {
+		recurring.Amount = req.Amount
+	}
+	if req.ToAmount != nil {
+		recurring.ToAmount = req.ToAmount
+	}
+	if req.Merchant != nil {
+		recurring.Merchant = *req.Merchant
+	}
+	if req.Description != nil {
+		recurring.Description = *req.Description
+	}
+	if req.Tags != nil {
+		recurring.Tags = models.StringList(*req.Tags)
+	}
+
+	scheduleChanged := false
+	if rrule := strings.ToUpper(strings.TrimSpace(req.RRule)); rrule != "" && rrule != recurring.RRule {
+		recurring.RRule = rrule
+		scheduleChanged = true
+	}
+	if req.StartDate != "" {
+		startDate, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+		recurring.StartDate = startDate
+		scheduleChanged = true
+	}
+	if req.EndDate != nil {
+		recurring.EndDate = nil
+		if *req.EndDate != "" {
+			endDate, err := time.Parse("2006-01-02", *req.EndDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, use YYYY-MM-DD"})
+				return
+			}
+			recurring.EndDate = &endDate
+		}
+		scheduleChanged = true
+	}
+
+	// 恢复暂停的模板时从今天开始排程，不补齐暂停期间
+	var resumeFrom time.Time
+	if req.Active != nil && *req.Active != recurring.Active {
+		recurring.Active = *req.Active
+		if recurring.Active {
+			resumeFrom = recurrence.Date(time.Now()).AddDate(0, 0, -1)
+			scheduleChanged = true
+		}
+	}
+
+	if err := validateRecurring(userID, &recurring); err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.CategoryID != nil || req.Category != "" || req.Type != "" {
+		categoryID, name := req.CategoryID, req.Category
+		if categoryID == nil && name == "" {
+			name = recurring.Category
+		}
+		var err error
+		recurring.CategoryID, recurring.Category, err = resolveTransactionCategory(database.DB, userID, recurring.Type, categoryID, name)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	if scheduleChanged {
+		if err := scheduleNextRun(&recurring, resumeFrom); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := database.DB.Save(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update recurring transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, recurring)
+}
+
+// DeleteRecurringTransaction 删除周期交易（软删除），已生成的交易保留
+func (h *RecurringHandler) DeleteRecurringTransaction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	recurringID := c.Param("id")
+
+	var recurring models.RecurringTransaction
+	if err := database.DB.Where("id = ? AND user_id = ?", recurringID, userID).First(&recurring).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "recurring transaction not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete recurring transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "recurring transaction deleted successfully"})
+}
+
+// RunRecurringTransactions 为所有到期（next_run 不晚于今天）的周期交易生成交易，停机期间错过的日期一并补齐。
+// 由后台调度定期调用；每笔交易与 next_run 的推进在同一事务中提交，中途停止或重复执行都不会重复生成。
+func RunRecurringTransactions(ctx context.Context, now time.Time) (int, error) {
+	today := recurrence.Date(now)
+
+	var due []models.RecurringTransaction
+	if err := database.DB.Where("active = ? AND next_run IS NOT NULL AND next_run <= ?", true, today).
+		Order("id").Find(&due).Error; err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for i := range due {
+		n, err := materializeRecurring(ctx, &due[i], today)
+		created += n
+		if errors.Is(err, context.Canceled) {
+			return created, err
+		}
+		if err != nil {
+			log.Printf("Recurring transaction %d: %v", due[i].ID, err)
+		}
+	}
+	return created, nil
+}
+
+// materializeRecurring 逐个生成模板到期的发生日期
+func materializeRecurring(ctx context.Context, recurring *models.RecurringTransaction, today time.Time) (int, error) {
+	rule, err := recurrence.Parse(recurring.RRule)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for i := 0; recurring.NextRun != nil && !recurring.NextRun.After(today) && i < maxRecurringCatchUp; i++ {
+		if err := ctx.Err(); err != nil {
+			return created, err
+		}
+
+		date := recurrence.Date(*recurring.NextRun)
+		inserted := false
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			// 含软删除：用户删掉的某次发生不会被重新生成
+			var count int64
+			if err := tx.Unscoped().Model(&models.Transaction{}).
+				Where("recurring_id = ? AND transaction_date = ?", recurring.ID, date).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				req, err := recurringTransactionRequest(tx, recurring, date)
+				if err != nil {
+					return err
+				}
+				if _, err := createTransaction(tx, recurring.UserID, req); err != nil {
+					return err
+				}
+				inserted = true
+			}
+
+			recurring.LastRun = &date
+			next := rule.Next(recurring.StartDate, date)
+			if recurring.EndDate != nil && next.After(*recurring.EndDate) {
+				recurring.NextRun = nil
+			} else {
+				recurring.NextRun = &next
+			}
+			return tx.Model(recurring).Select("last_run", "next_run").Updates(recurring).Error
+		})
+
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) {
+			// 模板已失效（如账户被删除），停用避免每次调度重复报错
+			if err := database.DB.Model(recurring).Update("active", false).Error; err != nil {
+				return created, errors.New(reqErr.Message + "; failed to deactivate: " + err.Error())
+			}
+			return created, errors.New("deactivated: " + reqErr.Message)
+		}
+		if err != nil {
+			return created, err
+		}
+		if inserted {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// recurringTransactionRequest 由模板构造创建交易的请求；模板分类已归档或删除时按名称匹配
+func recurringTransactionRequest(db *gorm.DB, recurring *models.RecurringTransaction, date time.Time) (CreateTransactionRequest, error) {
+	req := CreateTransactionRequest{
+		AccountID:       recurring.AccountID,
+		ToAccountID:     recurring.ToAccountID,
+		Type:            recurring.Type,
+		Amount:          recurring.Amount,
+		ToAmount:        recurring.ToAmount,
+		Category:        recurring.Category,
+		Merchant:        recurring.Merchant,
+		Description:     recurring.Description,
+		Tags:            recurring.Tags,
+		TransactionDate: date.Format("2006-01-02"),
+		recurringID:     &recurring.ID,
+	}
+	if recurring.CategoryID != nil {
+		var count int64
+		if err := db.Model(&models.Category{}).Where("id = ? AND archived = ?", *recurring.CategoryID, false).Count(&count).Error; err != nil {
+			return req, err
+		}
+		if count > 0 {
+			req.CategoryID = recurring.CategoryID
+		}
+	}
+	return req, nil
+}
+
+// scheduleNextRun 校验 RRule 并计算严格晚于 after 的下一次发生日期（after 为零值时从开始日期算起）
+func scheduleNextRun(recurring *models.RecurringTransaction, after time.Time) error {
+	rule, err := recurrence.Parse(recurring.RRule)
+	if err != nil {
+		return badRequest("invalid rrule: " + err.Error())
+	}
+
+	// 不重复生成已生成过的日期
+	if recurring.LastRun != nil && recurring.LastRun.After(after) {
+		after = *recurring.LastRun
+	}
+
+	var next time.Time
+	if after.IsZero() {
+		next = rule.First(recurring.StartDate)
+	} else {
+		next = rule.Next(recurring.StartDate, after)
+	}
+	recurring.NextRun = &next
+	if recurring.EndDate != nil && next.After(*recurring.EndDate) {
+		recurring.NextRun = nil
+	}
+	return nil
+}
+
+// validateRecurring 校验模板的账户和日期，规则同创建交易
+func validateRecurring(userID uint, recurring *models.RecurringTransaction) error {
+	if !isValidTransactionType(recurring.Type) {
+		return badRequest("invalid type")
+	}
+
+	var account models.Account
+	if err := database.DB.Where("id = ? AND user_id = ?", recurring.AccountID, userID).First(&account).Error; err != nil {
+		return badRequest("invalid account_id")
+	}
+	if recurring.Type == models.TransactionTransfer {
+		if recurring.ToAccountID == nil {
+			return badRequest("to_account_id is required for transfer")
+		}
+		var toAccount models.Account
+		if err := database.DB.Where("id = ? AND user_id = ?", *recurring.ToAccountID, userID).First(&toAccount).Error; err != nil {
+			return badRequest("invalid to_account_id")
+		}
+		if account.Currency == toAccount.Currency {
+			recurring.ToAmount = nil
+		} else if recurring.ToAmount == nil {
+			return badRequest("to_amount is required for transfers between accounts with different currencies")
+		}
+	} else {
+		recurring.ToAmount = nil
+	}
+
+	if recurring.EndDate != nil && recurring.EndDate.Before(recurring.StartDate) {
+		return badRequest("end_date must not be before start_date")
+	}
+	return nil
+}
@@ -0,0 +1,771 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type ReportHandler struct{}
+
+func NewReportHandler() *ReportHandler {
+	return &ReportHandler{}
+}
+
+// ReportTotals 收支合计（转账不计入），金额按交易日汇率折算为本位币
+type ReportTotals struct {
+	Income          models.Money    `json:"income"`
+	Expense         models.Money    `json:"expense"`
+	Investment      models.Money    `json:"investment"`
+	Net             models.Money    `json:"net"` // 收入 - 支出
+	IncomeCount     int64           `json:"income_count"`
+	ExpenseCount    int64           `json:"expense_count"`
+	InvestmentCount int64           `json:"investment_count"`
+	Currencies      []CurrencyTotal `json:"currencies"`            // 按账户币种的原币合计
+	Unconverted     []CurrencyTotal `json:"unconverted,omitempty"` // 缺少汇率、未计入上面本位币金额的原币合计
+}
+
+// CurrencyTotal 单一币种的原币收支合计
+type CurrencyTotal struct {
+	Currency   string       `json:"currency"`
+	Income     models.Money `json:"income"`
+	Expense    models.Money `json:"expense"`
+	Investment models.Money `json:"investment"`
+}
+
+// CategoryTotal 分类汇总（本位币），金额和笔数包含全部子分类
+type CategoryTotal struct {
+	CategoryID *uint           `json:"category_id,omitempty"`
+	Category   string          `json:"category"`
+	Amount     models.Money    `json:"amount"`
+	Count      int64           `json:"count"`
+	Percentage float64         `json:"percentage"` // 占该类型总额的百分比
+	Children   []CategoryTotal `json:"children,omitempty"`
+}
+
+// TagTotal 标签汇总；一笔交易有多个标签时计入每个标签
+type TagTotal struct {
+	TagID   uint         `json:"tag_id"`
+	Tag     string       `json:"tag"`
+	Income  models.Money `json:"income"`
+	Expense models.Money `json:"expense"`
+	Count   int64        `json:"count"`
+}
+
+// PeriodTotal 按日/按月的收支序列
+type PeriodTotal struct {
+	Period     string       `json:"period"`
+	Income     models.Money `json:"income"`
+	Expense    models.Money `json:"expense"`
+	Investment models.Money `json:"investment"`
+	Count      int64        `json:"count"`
+}
+
+// DailyReport 每日报表
+type DailyReport struct {
+	Date         string          `json:"date"`
+	BaseCurrency string          `json:"base_currency"`
+	Totals       ReportTotals    `json:"totals"`
+	Categories   []CategoryTotal `json:"categories"`
+	MissingRates []string        `json:"missing_rates,omitempty"` // 缺少汇率、未计入本位币金额的币种
+}
+
+// MonthlyReport 月度报表；未指定月份时为全年按月趋势
+type MonthlyReport struct {
+	Year         int             `json:"year"`
+	Month        int             `json:"month,omitempty"`
+	StartDate    string          `json:"start_date"`
+	EndDate      string          `json:"end_date"`
+	BaseCurrency string          `json:"base_currency"`
+	Totals       ReportTotals    `json:"totals"`
+	Categories   []CategoryTotal `json:"categories"`
+	Tags         []TagTotal      `json:"tags"`
+	Series       []PeriodTotal   `json:"series"`
+	MissingRates []string        `json:"missing_rates,omitempty"`
+}
+
+// CategorySummary 区间分类汇总
+type CategorySummary struct {
+	StartDate         string          `json:"start_date"`
+	EndDate           string          `json:"end_date"`
+	BaseCurrency      string          `json:"base_currency"`
+	Totals            ReportTotals    `json:"totals"`
+	ExpenseCategories []CategoryTotal `json:"expense_categories"`
+	IncomeCategories  []CategoryTotal `json:"income_categories"`
+	Tags              []TagTotal      `json:"tags"`
+	MissingRates      []string        `json:"missing_rates,omitempty"`
+}
+
+// NetWorthPoint 净资产序列中的一个点（区间最后一天，本位币）
+type NetWorthPoint struct {
+	Date        string                                 `json:"date"`
+	Total       models.Money                           `json:"total"`
+	Accounts    models.Money                           `json:"accounts"` // 账户余额合计
+	Holdings    models.Money                           `json:"holdings"` // 持仓市值合计，只在 holdings_as_of 及之后的点计入
+	ByType      map[models.AccountType]models.Money    `json:"by_type"`
+	ByLiquidity map[models.LiquidityLevel]models.Money `json:"by_liquidity"`          // 活钱桶 / 缓冲桶 / 长期资产
+	Unconverted map[string]models.Money                `json:"unconverted,omitempty"` // 缺少汇率、未计入合计的原币金额
+}
+
+// NetWorthReport 净资产时间序列
+type NetWorthReport struct {
+	BaseCurrency string          `json:"base_currency"`
+	Interval     string          `json:"interval"`
+	Start        string          `json:"start"`
+	End          string          `json:"end"`
+	Points       []NetWorthPoint `json:"points"`
+	HoldingsAsOf string          `json:"holdings_as_of,omitempty"` // 持仓没有历史市值，只计入不早于该日（今天）的点；为空表示各点均不含持仓
+	MissingRates []string        `json:"missing_rates,omitempty"`
+}
+
+// GetDailyReport 每日统计（默认今天）
+func (h *ReportHandler) GetDailyReport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if raw := c.Query("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date format, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	totals, err := reportTotals(userID, date, date, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	categories, err := categoryTotals(userID, date, date, models.TransactionExpense, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DailyReport{
+		Date:         date.Format("2006-01-02"),
+		BaseCurrency: cv.base,
+		Totals:       *totals,
+		Categories:   categories,
+		MissingRates: cv.missingRates(),
+	})
+}
+
+// GetMonthlyReport 月度统计：指定 month 时返回当月按日序列，否则返回全年按月趋势
+func (h *ReportHandler) GetMonthlyReport(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	now := time.Now()
+	year := now.Year()
+	if raw := c.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1900 || parsed > 9999 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+		year = parsed
+	}
+	month := 0
+	if raw := c.Query("month"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > 12 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month"})
+			return
+		}
+		month = parsed
+	}
+
+	var start, end time.Time
+	var periodFormat string
+	if month > 0 {
+		start = time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, -1)
+		periodFormat = "%Y-%m-%d"
+	} else {
+		start = time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(1, 0, -1)
+		periodFormat = "%Y-%m"
+	}
+
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	totals, err := reportTotals(userID, start, end, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	categories, err := categoryTotals(userID, start, end, models.TransactionExpense, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	tags, err := tagTotals(userID, start, end, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	series, err := periodTotals(userID, start, end, periodFormat, cv)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MonthlyReport{
+		Year:         year,
+		Month:        month,
+		StartDate:    start.Format("2006-01-02"),
+		EndDate:      end.Format("2006-01-02"),
+		BaseCurrency: cv.base,
+		Totals:       *totals,
+		Categories:   categories,
+		Tags:         tags,
+		Series:       series,
+		MissingRates: cv.missingRates(),
+	})
+}
+
+// GetCategorySummary 区间分类统计（默认本月）
+func (h *ReportHandler) GetCategorySummary(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	var err error
+	if raw := c.Query("start_date"); raw != "" {
+		if start, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if raw := c.Query("end_date"); raw != "" {
+		if end, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, use YYYY-MM-DD"})
+			return
+		}
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	summary, err := SummarizeCategories(userID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetNetWorth 净资产趋势（start、end 为 YYYY-MM-DD，interval 为 day/week/month，默认最近 12 个月按月），
+// 各账户余额和持仓按区间最后一天的汇率折算为本位币，并按账户类型和流动性分组合计
+func (h *ReportHandler) GetNetWorth(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	start, end, interval, err := parseBalanceRange(c, "month")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	closes, err := balanceIntervalCloses(start, end, interval)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := netWorthSeries(userID, start, closes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate report"})
+		return
+	}
+	report.Interval = interval
+	report.Start = start.Format("2006-01-02")
+	report.End = end.Format("2006-01-02")
+
+	c.JSON(http.StatusOK, report)
+}
+
+// SummarizeCategories 汇总 [start, end] 日期区间内的收支合计和分类明细，HTTP 接口和 MCP 工具共用
+func SummarizeCategories(userID uint, start, end time.Time) (*CategorySummary, error) {
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := reportTotals(userID, start, end, cv)
+	if err != nil {
+		return nil, err
+	}
+	expenseCategories, err := categoryTotals(userID, start, end, models.TransactionExpense, cv)
+	if err != nil {
+		return nil, err
+	}
+	incomeCategories, err := categoryTotals(userID, start, end, models.TransactionIncome, cv)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := tagTotals(userID, start, end, cv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CategorySummary{
+		StartDate:         start.Format("2006-01-02"),
+		EndDate:           end.Format("2006-01-02"),
+		BaseCurrency:      cv.base,
+		Totals:            *totals,
+		ExpenseCategories: expenseCategories,
+		IncomeCategories:  incomeCategories,
+		Tags:              tags,
+		MissingRates:      cv.missingRates(),
+	}, nil
+}
+
+// withoutSplitsSQL 没有拆分行的交易；有拆分行的交易在分类统计中按拆分行计入
+const withoutSplitsSQL = "NOT EXISTS (SELECT 1 FROM transaction_splits WHERE transaction_splits.transaction_id = transactions.id AND transaction_splits.deleted_at IS NULL)"
+
+// splitQuery 将交易查询展开为拆分行，每行对应一条拆分
+func splitQuery(query *gorm.DB) *gorm.DB {
+	return query.Joins("JOIN transaction_splits ON transaction_splits.transaction_id = transactions.id AND transaction_splits.deleted_at IS NULL")
+}
+
+// reportQuery 用户在 [start, end] 日期区间内的交易
+func reportQuery(userID uint, start, end time.Time) *gorm.DB {
+	return database.DB.Model(&models.Transaction{}).
+		Where("user_id = ? AND transaction_date >= ? AND transaction_date < ?", userID, start, end.AddDate(0, 0, 1))
+}
+
+// reportTotals 按类型合计，转账只是账户间移动，不计入收支
+func reportTotals(userID uint, start, end time.Time, cv *currencyConverter) (*ReportTotals, error) {
+	var rows []struct {
+		Type     models.TransactionType
+		Currency string
+		Day      string
+		Amount   models.Money
+		Count    int64
+	}
+	if err := reportQuery(userID, start, end).
+		Select("type, "+currencyDayColumns("transactions")+", SUM(amount) AS amount, COUNT(*) AS count").
+		Where("type <> ?", models.TransactionTransfer).
+		Group("type, currency, day").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	totals := &ReportTotals{}
+	native := make(map[string]*CurrencyTotal)
+	unconverted := make(map[string]*CurrencyTotal)
+	for _, r := range rows {
+		addCurrencyTotal(native, r.Currency, r.Type, r.Amount)
+		amount, ok := cv.convertDay(r.Amount, r.Currency, r.Day)
+		if !ok {
+			addCurrencyTotal(unconverted, r.Currency, r.Type, r.Amount)
+		}
+		switch r.Type {
+		case models.TransactionIncome:
+			totals.Income += amount
+			totals.IncomeCount += r.Count
+		case models.TransactionExpense:
+			totals.Expense += amount
+			totals.ExpenseCount += r.Count
+		case models.TransactionInvestment:
+			totals.Investment += amount
+			totals.InvestmentCount += r.Count
+		}
+	}
+	totals.Net = totals.Income - totals.Expense
+	totals.Currencies = sortedCurrencyTotals(native)
+	if len(unconverted) > 0 {
+		totals.Unconverted = sortedCurrencyTotals(unconverted)
+	}
+	return totals, nil
+}
+
+// addCurrencyTotal 将原币金额按交易类型累加到对应币种
+func addCurrencyTotal(totals map[string]*CurrencyTotal, currency string, txType models.TransactionType, amount models.Money) {
+	total := totals[currency]
+	if total == nil {
+		total = &CurrencyTotal{Currency: currency}
+		totals[currency] = total
+	}
+	switch txType {
+	case models.TransactionIncome:
+		total.Income += amount
+	case models.TransactionExpense:
+		total.Expense += amount
+	case models.TransactionInvestment:
+		total.Investment += amount
+	}
+}
+
+// sortedCurrencyTotals 按币种代码排序
+func sortedCurrencyTotals(totals map[string]*CurrencyTotal) []CurrencyTotal {
+	list := make([]CurrencyTotal, 0, len(totals))
+	for _, total := range totals {
+		list = append(list, *total)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+	return list
+}
+
+// categoryTotals 指定类型的分类明细：子分类金额逐级汇总到父分类，各级按金额降序
+func categoryTotals(userID uint, start, end time.Time, txType models.TransactionType, cv *currencyConverter) ([]CategoryTotal, error) {
+	type group struct {
+		CategoryID *uint
+		Category   string
+		Currency   string
+		Day        string
+		Amount     models.Money
+		Count      int64
+	}
+	var groups, splitGroups []group
+	if err := reportQuery(userID, start, end).
+		Select("category_id, COALESCE(NULLIF(TRIM(category), ''), '未分类') AS category, "+currencyDayColumns("transactions")+", SUM(amount) AS amount, COUNT(*) AS count").
+		Where("type = ?", txType).
+		Where(withoutSplitsSQL).
+		Group("category_id, COALESCE(NULLIF(TRIM(category), ''), '未分类'), currency, day").
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+	// 拆分的交易按拆分行计入各自的分类
+	if err := splitQuery(reportQuery(userID, start, end)).
+		Select("transaction_splits.category_id, COALESCE(NULLIF(TRIM(transaction_splits.category), ''), '未分类') AS category, "+currencyDayColumns("transactions")+", SUM(transaction_splits.amount) AS amount, COUNT(*) AS count").
+		Where("type = ?", txType).
+		Group("transaction_splits.category_id, COALESCE(NULLIF(TRIM(transaction_splits.category), ''), '未分类'), currency, day").
+		Scan(&splitGroups).Error; err != nil {
+		return nil, err
+	}
+	groups = append(groups, splitGroups...)
+
+	// 各币种、各日折算为本位币后按分类合并
+	type row struct {
+		CategoryID *uint
+		Category   string
+		Amount     models.Money
+		Count      int64
+	}
+	type rowKey struct {
+		id   uint
+		name string
+	}
+	var rows []*row
+	byKey := make(map[rowKey]*row)
+	for _, g := range groups {
+		key := rowKey{name: g.Category}
+		if g.CategoryID != nil {
+			key.id = *g.CategoryID
+		}
+		r := byKey[key]
+		if r == nil {
+			r = &row{CategoryID: g.CategoryID, Category: g.Category}
+			byKey[key] = r
+			rows = append(rows, r)
+		}
+		// 缺少汇率的金额不计入，已在合计的 unconverted 中列出
+		if amount, ok := cv.convertDay(g.Amount, g.Currency, g.Day); ok {
+			r.Amount += amount
+		}
+		r.Count += g.Count
+	}
+
+	// 含已删除分类，保证历史交易仍能归到父分类
+	var categories []models.Category
+	if err := database.DB.Unscoped().Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]models.Category, len(categories))
+	for _, cat := range categories {
+		byID[cat.ID] = cat
+	}
+
+	type node struct {
+		total    CategoryTotal
+		children map[uint]*node
+	}
+	nodes := make(map[uint]*node)
+	var roots []*node
+	getNode := func(cat models.Category) (*node, bool) {
+		if n, ok := nodes[cat.ID]; ok {
+			return n, false
+		}
+		id := cat.ID
+		n := &node{total: CategoryTotal{CategoryID: &id, Category: cat.Name}, children: make(map[uint]*node)}
+		nodes[cat.ID] = n
+		return n, true
+	}
+	unlinked := make(map[string]*node)
+
+	for _, r := range rows {
+		cat, ok := models.Category{}, false
+		if r.CategoryID != nil {
+			cat, ok = byID[*r.CategoryID]
+		}
+		if !ok {
+			// 未关联分类的交易按名称汇总为顶级项
+			n := unlinked[r.Category]
+			if n == nil {
+				n = &node{total: CategoryTotal{Category: r.Category}}
+				unlinked[r.Category] = n
+				roots = append(roots, n)
+			}
+			n.total.Amount += r.Amount
+			n.total.Count += r.Count
+			continue
+		}
+
+		// 自身及全部祖先累加（层级数上限防止异常数据成环）
+		var child *node
+		for depth := 0; depth <= len(byID); depth++ {
+			n, created := getNode(cat)
+			n.total.Amount += r.Amount
+			n.total.Count += r.Count
+			if child != nil {
+				n.children[*child.total.CategoryID] = child
+			}
+			parent, hasParent := models.Category{}, false
+			if cat.ParentID != nil {
+				parent, hasParent = byID[*cat.ParentID]
+			}
+			if !hasParent {
+				if created {
+					roots = append(roots, n)
+				}
+				break
+			}
+			child, cat = n, parent
+		}
+	}
+
+	var grandTotal models.Money
+	for _, n := range roots {
+		grandTotal += n.total.Amount
+	}
+
+	var build func(list []*node) []CategoryTotal
+	build = func(list []*node) []CategoryTotal {
+		result := make([]CategoryTotal, 0, len(list))
+		for _, n := range list {
+			total := n.total
+			if grandTotal > 0 {
+				total.Percentage = roundPercent(float64(total.Amount) / float64(grandTotal) * 100)
+			}
+			if len(n.children) > 0 {
+				children := make([]*node, 0, len(n.children))
+				for _, child := range n.children {
+					children = append(children, child)
+				}
+				total.Children = build(children)
+			}
+			result = append(result, total)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Amount != result[j].Amount {
+				return result[i].Amount > result[j].Amount
+			}
+			return result[i].Category < result[j].Category
+		})
+		return result
+	}
+	return build(roots), nil
+}
+
+// tagTotals 按标签汇总收支（不含转账），按支出降序
+func tagTotals(userID uint, start, end time.Time, cv *currencyConverter) ([]TagTotal, error) {
+	var groups []struct {
+		TagTotal
+		Currency string
+		Day      string
+	}
+	if err := database.DB.Table("transactions t").
+		Select(`tags.id AS tag_id, tags.name AS tag, `+currencyDayColumns("t")+`,
+			COALESCE(SUM(CASE WHEN t.type = 'income' THEN t.amount ELSE 0 END), 0) AS income,
+			COALESCE(SUM(CASE WHEN t.type = 'expense' THEN t.amount ELSE 0 END), 0) AS expense,
+			COUNT(*) AS count`).
+		Joins("JOIN transaction_tags tt ON tt.transaction_id = t.id").
+		Joins("JOIN tags ON tags.id = tt.tag_id AND tags.deleted_at IS NULL").
+		Where("t.user_id = ? AND t.deleted_at IS NULL AND t.transaction_date >= ? AND t.transaction_date < ?", userID, start, end.AddDate(0, 0, 1)).
+		Where("t.type <> ?", models.TransactionTransfer).
+		Group("tags.id, tags.name, currency, day").
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	totals := []TagTotal{}
+	index := make(map[uint]int)
+	for _, g := range groups {
+		i, ok := index[g.TagID]
+		if !ok {
+			i = len(totals)
+			index[g.TagID] = i
+			totals = append(totals, TagTotal{TagID: g.TagID, Tag: g.Tag})
+		}
+		if income, ok := cv.convertDay(g.Income, g.Currency, g.Day); ok {
+			totals[i].Income += income
+		}
+		if expense, ok := cv.convertDay(g.Expense, g.Currency, g.Day); ok {
+			totals[i].Expense += expense
+		}
+		totals[i].Count += g.Count
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Expense != totals[j].Expense {
+			return totals[i].Expense > totals[j].Expense
+		}
+		return totals[i].Tag < totals[j].Tag
+	})
+	return totals, nil
+}
+
+// periodTotals 按 strftime 格式（%Y-%m-%d 或 %Y-%m）分组的收支序列
+func periodTotals(userID uint, start, end time.Time, format string, cv *currencyConverter) ([]PeriodTotal, error) {
+	var groups []struct {
+		PeriodTotal
+		Currency string
+		Day      string
+	}
+	if err := reportQuery(userID, start, end).
+		Select(`strftime(?, transaction_date) AS period, `+currencyDayColumns("transactions")+`,
+			COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE 0 END), 0) AS income,
+			COALESCE(SUM(CASE WHEN type = 'expense' THEN amount ELSE 0 END), 0) AS expense,
+			COALESCE(SUM(CASE WHEN type = 'investment' THEN amount ELSE 0 END), 0) AS investment,
+			COUNT(*) AS count`, format).
+		Where("type <> ?", models.TransactionTransfer).
+		Group("period, currency, day").
+		Order("period").
+		Scan(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	series := []PeriodTotal{}
+	for _, g := range groups {
+		if n := len(series); n == 0 || series[n-1].Period != g.Period {
+			series = append(series, PeriodTotal{Period: g.Period})
+		}
+		p := &series[len(series)-1]
+		if income, ok := cv.convertDay(g.Income, g.Currency, g.Day); ok {
+			p.Income += income
+		}
+		if expense, ok := cv.convertDay(g.Expense, g.Currency, g.Day); ok {
+			p.Expense += expense
+		}
+		if investment, ok := cv.convertDay(g.Investment, g.Currency, g.Day); ok {
+			p.Investment += investment
+		}
+		p.Count += g.Count
+	}
+	return series, nil
+}
+
+// netWorthSeries 计算各 closes 日期的净资产。
+// 持仓没有历史价格，也不保留已删除持仓的历史，只有不早于今天的点按当前市值（未设置时按成本）计入，
+// 过去的点只含账户余额。
+func netWorthSeries(userID uint, start time.Time, closes []time.Time) (*NetWorthReport, error) {
+	cv, err := loadCurrencyConverter(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []models.Account
+	if err := database.DB.Where("user_id = ?", userID).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	var holdings []models.Holding
+	if err := database.DB.Joins("Account").Where("holdings.user_id = ?", userID).Find(&holdings).Error; err != nil {
+		return nil, err
+	}
+
+	points := make([]NetWorthPoint, len(closes))
+	for i, closing := range closes {
+		points[i] = NetWorthPoint{
+			Date:        closing.Format("2006-01-02"),
+			ByType:      make(map[models.AccountType]models.Money),
+			ByLiquidity: make(map[models.LiquidityLevel]models.Money),
+		}
+	}
+	add := func(p *NetWorthPoint, account models.Account, amount models.Money) {
+		p.Total += amount
+		p.ByType[account.Type] += amount
+		p.ByLiquidity[account.LiquidityLevel] += amount
+	}
+
+	_, series, err := balanceSeries(userID, accounts, start, closes)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		for i, point := range series[account.ID] {
+			amount, ok := cv.convert(point.Balance, account.Currency, closes[i])
+			if !ok {
+				addUnconverted(&points[i], account.Currency, point.Balance)
+				continue
+			}
+			points[i].Accounts += amount
+			add(&points[i], account, amount)
+		}
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	holdingsAsOf := ""
+	if len(closes) > 0 && !closes[len(closes)-1].Before(today) {
+		holdingsAsOf = today.Format("2006-01-02")
+	}
+	for _, holding := range holdings {
+		// 所属账户已删除时 Joins 取不到账户
+		if holding.Account.ID == 0 {
+			continue
+		}
+		value := holding.CostBasisTotal
+		if holding.MarketValue != nil {
+			value = *holding.MarketValue
+		}
+		for i, closing := range closes {
+			if closing.Before(today) {
+				continue
+			}
+			amount, ok := cv.convert(value, holding.Currency, closing)
+			if !ok {
+				addUnconverted(&points[i], holding.Currency, value)
+				continue
+			}
+			points[i].Holdings += amount
+			add(&points[i], holding.Account, amount)
+		}
+	}
+
+	return &NetWorthReport{
+		BaseCurrency: cv.base,
+		Points:       points,
+		HoldingsAsOf: holdingsAsOf,
+		MissingRates: cv.missingRates(),
+	}, nil
+}
+
+// addUnconverted 记录净资产点中缺少汇率的原币金额
+func addUnconverted(p *NetWorthPoint, currency string, amount models.Money) {
+	if amount == 0 {
+		return
+	}
+	if p.Unconverted == nil {
+		p.Unconverted = make(map[string]models.Money)
+	}
+	p.Unconverted[currency] += amount
+}
+
+func roundPercent(v float64) float64 {
+	return float64(int64(v*100+0.5)) / 100
+}
@@ -0,0 +1,502 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"github.com/jasxu/fi_system/internal/rules"
+	"gorm.io/gorm"
+)
+
+type RuleHandler struct{}
+
+func NewRuleHandler() *RuleHandler {
+	return &RuleHandler{}
+}
+
+type CreateRuleRequest struct {
+	Name               string                 `json:"name" binding:"required"`
+	Priority           int                    `json:"priority"`
+	Enabled            *bool                  `json:"enabled,omitempty"` // 默认启用
+	StopProcessing     bool                   `json:"stop_processing"`
+	MerchantPattern    string                 `json:"merchant_pattern,omitempty"`
+	DescriptionPattern string                 `json:"description_pattern,omitempty"`
+	Keyword            string                 `json:"keyword,omitempty"`
+	MinAmount          *models.Money          `json:"min_amount,omitempty"`
+	MaxAmount          *models.Money          `json:"max_amount,omitempty"`
+	AccountID          *uint                  `json:"account_id,omitempty"`
+	TransactionType    models.TransactionType `json:"transaction_type,omitempty"`
+	SetCategoryID      *uint                  `json:"set_category_id,omitempty"`
+	SetMerchant        string                 `json:"set_merchant,omitempty"`
+	AddTags            []string               `json:"add_tags,omitempty"`
+}
+
+// UpdateRuleRequest 指针字段为 nil 表示不修改；条件类字符串传空串、金额/账户/分类传 0 表示清除
+type UpdateRuleRequest struct {
+	Name               string                  `json:"name"`
+	Priority           *int                    `json:"priority"`
+	Enabled            *bool                   `json:"enabled"`
+	StopProcessing     *bool                   `json:"stop_processing"`
+	MerchantPattern    *string                 `json:"merchant_pattern"`
+	DescriptionPattern *string                 `json:"description_pattern"`
+	Keyword            *string                 `json:"keyword"`
+	MinAmount          *models.Money           `json:"min_amount"`
+	MaxAmount          *models.Money           `json:"max_amount"`
+	AccountID          *uint                   `json:"account_id"`
+	TransactionType    *models.TransactionType `json:"transaction_type"`
+	SetCategoryID      *uint                   `json:"set_category_id"`
+	SetMerchant        *string                 `json:"set_merchant"`
+	AddTags            *[]string               `json:"add_tags"`
+}
+
+// ApplyRulesRequest 对已有交易重新执行规则
+type ApplyRulesRequest struct {
+	StartDate         string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate           string `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	AccountID         *uint  `json:"account_id,omitempty"`
+	DryRun            bool   `json:"dry_run"`
+	OverwriteCategory bool   `json:"overwrite_category"` // 默认只为未分类的交易设置分类
+}
+
+// RuleChange 单笔交易将被（或已被）修改的内容
+type RuleChange struct {
+	TransactionID   uint      `json:"transaction_id"`
+	TransactionDate time.Time `json:"transaction_date"`
+	RuleIDs         []uint    `json:"rule_ids"`
+	CategoryFrom    string    `json:"category_from,omitempty"`
+	CategoryTo      string    `json:"category_to,omitempty"`
+	CategoryIDTo    *uint     `json:"category_id_to,omitempty"`
+	MerchantFrom    string    `json:"merchant_from,omitempty"`
+	MerchantTo      string    `json:"merchant_to,omitempty"`
+	AddTags         []string  `json:"add_tags,omitempty"`
+}
+
+type ApplyRulesResponse struct {
+	DryRun  bool         `json:"dry_run"`
+	Scanned int          `json:"scanned"`
+	Changed int          `json:"changed"`
+	Changes []RuleChange `json:"changes"`
+}
+
+// GetRules 获取规则列表（按执行顺序）
+func (h *RuleHandler) GetRules(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var list []models.Rule
+	if err := database.DB.Where("user_id = ?", userID).Order("priority, id").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetRule 获取单个规则
+func (h *RuleHandler) GetRule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	ruleID := c.Param("id")
+
+	var rule models.Rule
+	if err := database.DB.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// CreateRule 创建规则
+func (h *RuleHandler) CreateRule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.Rule{
+		UserID:             userID,
+		Name:               strings.TrimSpace(req.Name),
+		Priority:           req.Priority,
+		Enabled:            req.Enabled == nil || *req.Enabled,
+		StopProcessing:     req.StopProcessing,
+		MerchantPattern:    req.MerchantPattern,
+		DescriptionPattern: req.DescriptionPattern,
+		Keyword:            req.Keyword,
+		MinAmount:          req.MinAmount,
+		MaxAmount:          req.MaxAmount,
+		AccountID:          req.AccountID,
+		TransactionType:    req.TransactionType,
+		SetCategoryID:      req.SetCategoryID,
+		SetMerchant:        strings.TrimSpace(req.SetMerchant),
+		AddTags:            models.StringList(req.AddTags),
+	}
+
+	if err := validateRule(userID, &rule); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create rule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule 更新规则
+func (h *RuleHandler) UpdateRule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	ruleID := c.Param("id")
+
+	var rule models.Rule
+	if err := database.DB.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+
+	var req UpdateRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	if name := strings.TrimSpace(req.Name); name != "" {
+		rule.Name = name
+	}
+	if req.Priority != nil {
+		rule.Priority = *req.Priority
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	if req.StopProcessing != nil {
+		rule.StopProcessing = *req.StopProcessing
+	}
+	if req.MerchantPattern != nil {
+		rule.MerchantPattern = *req.MerchantPattern
+	}
+	if req.DescriptionPattern != nil {
+		rule.DescriptionPattern = *req.DescriptionPattern
+	}
+	if req.Keyword != nil {
+		rule.Keyword = *req.Keyword
+	}
+	if req.MinAmount != nil {
+		rule.MinAmount = nonZeroMoney(*req.MinAmount)
+	}
+	if req.MaxAmount != nil {
+		rule.MaxAmount = nonZeroMoney(*req.MaxAmount)
+	}
+	if req.AccountID != nil {
+		rule.AccountID = nonZeroID(*req.AccountID)
+	}
+	if req.TransactionType != nil {
+		rule.TransactionType = *req.TransactionType
+	}
+	if req.SetCategoryID != nil {
+		rule.SetCategoryID = nonZeroID(*req.SetCategoryID)
+	}
+	if req.SetMerchant != nil {
+		rule.SetMerchant = strings.TrimSpace(*req.SetMerchant)
+	}
+	if req.AddTags != nil {
+		rule.AddTags = models.StringList(*req.AddTags)
+	}
+
+	if err := validateRule(userID, &rule); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule 删除规则（软删除）
+func (h *RuleHandler) DeleteRule(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	ruleID := c.Param("id")
+
+	var rule models.Rule
+	if err := database.DB.Where("id = ? AND user_id = ?", ruleID, userID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "rule deleted successfully"})
+}
+
+// ApplyRules 对日期区间内的已有交易重新执行规则；dry_run 时只返回预览，不修改数据
+func (h *RuleHandler) ApplyRules(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req ApplyRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, use YYYY-MM-DD"})
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", req.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, use YYYY-MM-DD"})
+		return
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+
+	response := ApplyRulesResponse{DryRun: req.DryRun, Changes: []RuleChange{}}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		ruleSet, err := loadRules(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		// 已对账的交易被锁定，不参与规则回溯
+		query := tx.Preload("Tags").
+			Where("user_id = ? AND transaction_date >= ? AND transaction_date < ?", userID, startDate, endDate.AddDate(0, 0, 1)).
+			Where("status <> ?", models.TransactionReconciled)
+		if req.AccountID != nil {
+			query = query.Where("account_id = ?", *req.AccountID)
+		}
+		var transactions []models.Transaction
+		if err := query.Order("transaction_date, id").Find(&transactions).Error; err != nil {
+			return err
+		}
+		response.Scanned = len(transactions)
+
+		categoryNames := make(map[uint]string)
+		var categories []models.Category
+		if err := tx.Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+			return err
+		}
+		for _, cat := range categories {
+			categoryNames[cat.ID] = cat.Name
+		}
+
+		for i := range transactions {
+			transaction := &transactions[i]
+			change, ok := planRuleChange(ruleSet, transaction, categoryNames, req.OverwriteCategory)
+			if !ok {
+				continue
+			}
+			response.Changes = append(response.Changes, change)
+			if req.DryRun {
+				continue
+			}
+			if err := applyRuleChange(tx, userID, transaction, change); err != nil {
+				return err
+			}
+		}
+		response.Changed = len(response.Changes)
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// planRuleChange 计算规则对一笔交易的修改，无修改时返回 false
+func planRuleChange(ruleSet []*rules.Rule, transaction *models.Transaction, categoryNames map[uint]string, overwriteCategory bool) (RuleChange, bool) {
+	result := rules.Apply(ruleSet, ruleInput(transaction))
+	change := RuleChange{TransactionID: transaction.ID, TransactionDate: transaction.TransactionDate, RuleIDs: result.RuleIDs}
+	changed := false
+
+	if result.CategoryID != nil && (transaction.CategoryID == nil || overwriteCategory) &&
+		(transaction.CategoryID == nil || *transaction.CategoryID != *result.CategoryID) {
+		if name, ok := categoryNames[*result.CategoryID]; ok {
+			change.CategoryFrom, change.CategoryTo, change.CategoryIDTo = transaction.Category, name, result.CategoryID
+			changed = true
+		}
+	}
+	if result.Merchant != "" && result.Merchant != transaction.Merchant {
+		change.MerchantFrom, change.MerchantTo = transaction.Merchant, result.Merchant
+		changed = true
+	}
+
+	existing := make(map[string]bool, len(transaction.Tags))
+	for _, tag := range transaction.Tags {
+		existing[tag.Name] = true
+	}
+	for _, tag := range result.Tags {
+		if !existing[tag] {
+			change.AddTags = append(change.AddTags, tag)
+			changed = true
+		}
+	}
+	return change, changed
+}
+
+func applyRuleChange(tx *gorm.DB, userID uint, transaction *models.Transaction, change RuleChange) error {
+	updates := map[string]interface{}{}
+	if change.CategoryIDTo != nil {
+		updates["category_id"] = *change.CategoryIDTo
+		updates["category"] = change.CategoryTo
+	}
+	if change.MerchantTo != "" {
+		updates["merchant"] = change.MerchantTo
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(transaction).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	if len(change.AddTags) > 0 {
+		tags, err := findOrCreateTags(tx, userID, change.AddTags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(transaction).Association("Tags").Append(tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRules 按执行顺序加载并编译用户启用的规则
+func loadRules(db *gorm.DB, userID uint) ([]*rules.Rule, error) {
+	var list []models.Rule
+	if err := db.Where("user_id = ? AND enabled = ?", userID, true).Order("priority, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	// 已归档或已删除的目标分类不再使用
+	var categories []models.Category
+	if err := db.Where("user_id = ? AND archived = ?", userID, false).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	kinds := make(map[uint]models.CategoryKind, len(categories))
+	for _, cat := range categories {
+		kinds[cat.ID] = cat.Kind
+	}
+
+	compiled := make([]*rules.Rule, 0, len(list))
+	for _, rule := range list {
+		var kind models.CategoryKind
+		if rule.SetCategoryID != nil {
+			kind = kinds[*rule.SetCategoryID]
+		}
+		r, err := rules.Compile(rule, kind)
+		if err != nil {
+			continue // 保存时已校验，这里跳过异常数据
+		}
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// applyRulesToRequest 对新建交易执行规则：规范化商户名、追加标签，请求未指定分类时设置分类。
+// overrideCategory 为 true 时规则分类优先（用于导入账单自带的通用分类）。
+func applyRulesToRequest(ruleSet []*rules.Rule, req *CreateTransactionRequest, overrideCategory bool) {
+	if len(ruleSet) == 0 {
+		return
+	}
+
+	result := rules.Apply(ruleSet, rules.Input{
+		AccountID:   req.AccountID,
+		Type:        req.Type,
+		Amount:      req.Amount,
+		Merchant:    req.Merchant,
+		Description: req.Description,
+	})
+	if result.CategoryID != nil && (overrideCategory || (req.CategoryID == nil && strings.TrimSpace(req.Category) == "")) {
+		req.CategoryID = result.CategoryID
+	}
+	if result.Merchant != "" {
+		req.Merchant = result.Merchant
+	}
+	req.Tags = append(req.Tags, result.Tags...)
+}
+
+func ruleInput(transaction *models.Transaction) rules.Input {
+	return rules.Input{
+		AccountID:   transaction.AccountID,
+		Type:        transaction.Type,
+		Amount:      transaction.Amount,
+		Merchant:    transaction.Merchant,
+		Description: transaction.Description,
+	}
+}
+
+// validateRule 校验规则条件/动作，以及账户和目标分类归属
+func validateRule(userID uint, rule *models.Rule) error {
+	if rule.Name == "" {
+		return badRequest("name is required")
+	}
+	if rule.TransactionType != "" && !isValidTransactionType(rule.TransactionType) {
+		return badRequest("invalid transaction_type")
+	}
+	if err := rules.Validate(*rule); err != nil {
+		return badRequest(err.Error())
+	}
+	if rule.AccountID != nil {
+		var account models.Account
+		if err := database.DB.Where("id = ? AND user_id = ?", *rule.AccountID, userID).First(&account).Error; err != nil {
+			return badRequest("invalid account_id")
+		}
+	}
+	if rule.SetCategoryID != nil {
+		var category models.Category
+		if err := database.DB.Where("id = ? AND user_id = ?", *rule.SetCategoryID, userID).First(&category).Error; err != nil {
+			return badRequest("invalid set_category_id")
+		}
+		if rule.TransactionType == models.TransactionTransfer {
+			return badRequest("transfers cannot be categorized by rules")
+		}
+		if rule.TransactionType != "" && models.CategoryKindFor(rule.TransactionType) != category.Kind {
+			return badRequest("set_category_id kind does not match transaction_type")
+		}
+	}
+	return nil
+}
+
+func isValidTransactionType(t models.TransactionType) bool {
+	switch t {
+	case models.TransactionIncome, models.TransactionExpense, models.TransactionTransfer, models.TransactionInvestment:
+		return true
+	}
+	return false
+}
+
+func nonZeroID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func nonZeroMoney(m models.Money) *models.Money {
+	if m == 0 {
+		return nil
+	}
+	return &m
+}
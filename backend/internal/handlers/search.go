@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jasxu/fi_system/internal/database"
+	"gorm.io/gorm"
+)
+
+// trigramMinLength trigram 分词器只能匹配不少于 3 个字符的词
+const trigramMinLength = 3
+
+// searchTransactions 按关键词搜索商户、备注和分类，多个关键词（空格分隔）须同时命中
+func searchTransactions(query *gorm.DB, q string) *gorm.DB {
+	var phrases []string
+	for _, term := range strings.Fields(q) {
+		if database.SearchEnabled && utf8.RuneCountInString(term) >= trigramMinLength {
+			phrases = append(phrases, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+			continue
+		}
+		// 短词（如两个字的商户名）无法走 trigram 索引，退化为子串匹配
+		pattern := "%" + escapeLike(term) + "%"
+		query = query.Where(`merchant LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR category LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern)
+	}
+	if len(phrases) > 0 {
+		query = query.Where("id IN (SELECT rowid FROM transactions_fts WHERE transactions_fts MATCH ?)", strings.Join(phrases, " "))
+	}
+	return query
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jasxu/fi_system/internal/database"
+	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
+)
+
+type TagHandler struct{}
+
+func NewTagHandler() *TagHandler {
+	return &TagHandler{}
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color,omitempty"`
+}
+
+type UpdateTagRequest struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color"`
+}
+
+// TransactionTagsRequest 为交易添加标签：tag_ids 为已有标签，tags 为标签名（不存在时自动创建）
+type TransactionTagsRequest struct {
+	TagIDs []uint   `json:"tag_ids"`
+	Tags   []string `json:"tags"`
+}
+
+// GetTags 获取标签列表
+func (h *TagHandler) GetTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var tags []models.Tag
+	if err := database.DB.Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag 创建标签
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag := models.Tag{UserID: userID, Name: strings.TrimSpace(req.Name), Color: req.Color}
+	if tag.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	taken, err := tagNameTaken(userID, tag.Name, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create tag"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+		return
+	}
+
+	if err := database.DB.Create(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag 更新标签
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tagID := c.Param("id")
+
+	var tag models.Tag
+	if err := database.DB.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	var req UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 更新字段
+	if name := strings.TrimSpace(req.Name); name != "" {
+		taken, err := tagNameTaken(userID, name, tag.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update tag"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "tag already exists"})
+			return
+		}
+		tag.Name = name
+	}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+
+	if err := database.DB.Save(&tag).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag 删除标签（软删除），同时解除与交易的关联
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	tagID := c.Param("id")
+
+	var tag models.Tag
+	if err := database.DB.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM transaction_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tag deleted successfully"})
+}
+
+// AddTransactionTags 为交易添加标签
+func (h *TagHandler) AddTransactionTags(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	transactionID := c.Param("id")
+
+	var transaction models.Transaction
+	if err := database.DB.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
+
+	var req TransactionTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.TagIDs) == 0 && len(req.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_ids or tags is required"})
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var tags []models.Tag
+		if len(req.TagIDs) > 0 {
+			if err := tx.Where("id IN ? AND user_id = ?", req.TagIDs, userID).Find(&tags).Error; err != nil {
+				return err
+			}
+			if len(tags) != len(uniqueIDs(req.TagIDs)) {
+				return badRequest("invalid tag_ids")
+			}
+		}
+		named, err := findOrCreateTags(tx, userID, req.Tags)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&transaction).Association("Tags").Append(append(tags, named...))
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := database.DB.Preload("Tags").First(&transaction, transaction.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// RemoveTransactionTag 移除交易的标签
+func (h *TagHandler) RemoveTransactionTag(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	transactionID := c.Param("id")
+
+	var transaction models.Transaction
+	if err := database.DB.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
+		return
+	}
+
+	var tag models.Tag
+	if err := database.DB.Where("id = ? AND user_id = ?", c.Param("tag_id"), userID).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "tag not found"})
+		return
+	}
+
+	if err := database.DB.Model(&transaction).Association("Tags").Delete(&tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tag removed successfully"})
+}
+
+func tagNameTaken(userID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	if err := database.DB.Model(&models.Tag{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// findOrCreateTags 按名称查找用户的标签，不存在的自动创建；名称去除首尾空格并去重
+func findOrCreateTags(db *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag models.Tag
+		if err := db.Where(models.Tag{UserID: userID, Name: name}).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/jasxu/fi_system/internal/database"
 	"github.com/jasxu/fi_system/internal/models"
+	"gorm.io/gorm"
 )
 
 type TransactionHandler struct{}
@@ -16,33 +21,124 @@ func NewTransactionHandler() *TransactionHandler {
 }
 
 type CreateTransactionRequest struct {
-	AccountID       uint                    `json:"account_id" binding:"required"`
-	ToAccountID     *uint                   `json:"to_account_id,omitempty"`
-	Type            models.TransactionType  `json:"type" binding:"required"`
-	Amount          float64                 `json:"amount" binding:"required,gt=0"`
-	Category        string                  `json:"category,omitempty"`
-	Merchant        string                  `json:"merchant,omitempty"`
-	Description     string                  `json:"description,omitempty"`
-	TransactionDate string                  `json:"transaction_date" binding:"required"` // YYYY-MM-DD
+	AccountID       uint                     `json:"account_id" binding:"required"`
+	ToAccountID     *uint                    `json:"to_account_id,omitempty"`
+	Type            models.TransactionType   `json:"type" binding:"required"`
+	Amount          models.Money             `json:"amount" binding:"required,gt=0"`
+	ToAmount        *models.Money            `json:"to_amount,omitempty" binding:"omitempty,gt=0"` // 两个账户币种不同的转账必填：目标账户入账金额
+	CategoryID      *uint                    `json:"category_id,omitempty"`
+	Category        string                   `json:"category,omitempty"` // 未指定 category_id 时按名称匹配或自动创建分类
+	Merchant        string                   `json:"merchant,omitempty"`
+	Description     string                   `json:"description,omitempty"`
+	Tags            []string                 `json:"tags,omitempty"`                      // 标签名，不存在时自动创建
+	TransactionDate string                   `json:"transaction_date" binding:"required"` // YYYY-MM-DD
+	Status          models.TransactionStatus `json:"status,omitempty" binding:"omitempty,oneof=uncleared cleared"`
+	Splits          []SplitRequest           `json:"splits,omitempty"` // 按分类拆分，金额合计须等于 amount
+
+	recurringID  *uint  // 由周期交易生成时设置
+	externalID   string // 账单导入时的第三方交易单号
+	rulesApplied bool   // 调用方已执行自动分类规则（账单导入在去重前执行）
 }
 
 type UpdateTransactionRequest struct {
-	AccountID       uint                    `json:"account_id"`
-	ToAccountID     *uint                   `json:"to_account_id,omitempty"`
-	Type            models.TransactionType  `json:"type"`
-	Amount          float64                 `json:"amount" binding:"omitempty,gt=0"`
-	Category        string                  `json:"category"`
-	Merchant        string                  `json:"merchant"`
-	Description     string                  `json:"description"`
-	TransactionDate string                  `json:"transaction_date"` // YYYY-MM-DD
+	AccountID       uint                     `json:"account_id"`
+	ToAccountID     *uint                    `json:"to_account_id,omitempty"`
+	Type            models.TransactionType   `json:"type"`
+	Amount          models.Money             `json:"amount" binding:"omitempty,gt=0"`
+	ToAmount        *models.Money            `json:"to_amount" binding:"omitempty,gt=0"`
+	CategoryID      *uint                    `json:"category_id"` // 0 表示清除分类
+	Category        string                   `json:"category"`
+	Merchant        string                   `json:"merchant"`
+	Description     string                   `json:"description"`
+	Tags            *[]string                `json:"tags"`                                               // 提供时替换全部标签
+	TransactionDate string                   `json:"transaction_date"`                                   // YYYY-MM-DD
+	Status          models.TransactionStatus `json:"status" binding:"omitempty,oneof=uncleared cleared"` // reconciled 只能通过对账设置
+	Splits          *[]SplitRequest          `json:"splits"`                                             // 提供时替换全部拆分行，空数组表示取消拆分
 }
 
-// GetTransactions 获取交易列表（支持筛选）
+// SplitRequest 拆分行，category_id / category 的用法与交易相同
+type SplitRequest struct {
+	CategoryID  *uint        `json:"category_id,omitempty"`
+	Category    string       `json:"category,omitempty"`
+	Amount      models.Money `json:"amount"`
+	Description string       `json:"description,omitempty"`
+}
+
+// TransactionPage 分页的交易列表
+type TransactionPage struct {
+	Items      []models.Transaction `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	TotalCount int64                `json:"total_count"`
+}
+
+// GetTransactions 获取交易列表（支持筛选、排序和游标分页；all=true 时返回不分页的完整数组）
 func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	query := database.DB.Where("user_id = ?", userID)
+	query, err := filterTransactions(database.DB.Where("user_id = ?", userID), c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	sort, err := parseTransactionSort(c.Query("sort"), c.Query("order"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 兼容旧客户端：一次返回全部结果
+	if c.Query("all") == "true" {
+		var transactions []models.Transaction
+		if err := query.Preload("Tags").Preload("Splits").Order(sort.orderClause()).Find(&transactions).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transactions"})
+			return
+		}
+		c.JSON(http.StatusOK, transactions)
+		return
+	}
+
+	limit, err := parsePageLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var page TransactionPage
+	if err := query.Session(&gorm.Session{}).Model(&models.Transaction{}).Count(&page.TotalCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transactions"})
+		return
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeTransactionCursor(raw)
+		if err == nil {
+			query, err = cursor.applyAfter(query, sort)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 多取一条判断是否还有下一页
+	if err := query.Preload("Tags").Preload("Splits").Order(sort.orderClause()).Limit(limit + 1).Find(&page.Items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transactions"})
+		return
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = newTransactionCursor(sort, page.Items[limit-1]).encode()
+	}
+	if page.Items == nil {
+		page.Items = []models.Transaction{}
+	}
+
+	c.JSON(http.StatusOK, page)
+}
 
+// filterTransactions 应用列表筛选条件
+func filterTransactions(query *gorm.DB, c *gin.Context) (*gorm.DB, error) {
 	// 筛选：账户
 	if accountID := c.Query("account_id"); accountID != "" {
 		query = query.Where("account_id = ? OR to_account_id = ?", accountID, accountID)
@@ -53,6 +149,11 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		query = query.Where("type = ?", txType)
 	}
 
+	// 筛选：对账状态
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
 	// 筛选：日期范围
 	if startDate := c.Query("start_date"); startDate != "" {
 		query = query.Where("transaction_date >= ?", startDate)
@@ -61,13 +162,95 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		query = query.Where("transaction_date <= ?", endDate)
 	}
 
-	var transactions []models.Transaction
-	if err := query.Order("transaction_date DESC, created_at DESC").Find(&transactions).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transactions"})
-		return
+	// 筛选：金额范围
+	if raw := c.Query("min_amount"); raw != "" {
+		amount, err := models.ParseMoney(raw)
+		if err != nil {
+			return nil, badRequest("invalid min_amount")
+		}
+		query = query.Where("amount >= ?", amount)
+	}
+	if raw := c.Query("max_amount"); raw != "" {
+		amount, err := models.ParseMoney(raw)
+		if err != nil {
+			return nil, badRequest("invalid max_amount")
+		}
+		query = query.Where("amount <= ?", amount)
+	}
+
+	// 筛选：分类（可重复传参或逗号分隔，匹配任一）
+	var categories []string
+	for _, raw := range c.QueryArray("category") {
+		for _, category := range strings.Split(raw, ",") {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+	}
+	if len(categories) > 0 {
+		query = query.Where("category IN ?", categories)
+	}
+
+	// 筛选：分类 id（包含子分类）
+	if raw := c.Query("category_id"); raw != "" {
+		categoryID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, badRequest("invalid category_id")
+		}
+		ids, err := categoryWithDescendants(c.GetUint("user_id"), uint(categoryID))
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("category_id IN ?", ids)
 	}
 
-	c.JSON(http.StatusOK, transactions)
+	// 筛选：标签（tag_id 可重复传参或逗号分隔；tag_mode=any 命中任一，all 须全部命中）
+	if raw := c.QueryArray("tag_id"); len(raw) > 0 {
+		var tagIDs []uint
+		for _, value := range raw {
+			for _, part := range strings.Split(value, ",") {
+				tagID, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+				if err != nil {
+					return nil, badRequest("invalid tag_id")
+				}
+				tagIDs = append(tagIDs, uint(tagID))
+			}
+		}
+		tagIDs = uniqueIDs(tagIDs)
+
+		switch c.DefaultQuery("tag_mode", "any") {
+		case "any":
+			query = query.Where("id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id IN ?)", tagIDs)
+		case "all":
+			query = query.Where(`id IN (SELECT transaction_id FROM transaction_tags WHERE tag_id IN ?
+				GROUP BY transaction_id HAVING COUNT(DISTINCT tag_id) = ?)`, tagIDs, len(tagIDs))
+		default:
+			return nil, badRequest("tag_mode must be any or all")
+		}
+	}
+
+	// 筛选：商户（子串匹配）
+	if merchant := strings.TrimSpace(c.Query("merchant")); merchant != "" {
+		query = query.Where(`merchant LIKE ? ESCAPE '\'`, "%"+escapeLike(merchant)+"%")
+	}
+
+	// 筛选：是否有备注
+	switch c.Query("has_description") {
+	case "":
+	case "true":
+		query = query.Where("TRIM(COALESCE(description, '')) <> ''")
+	case "false":
+		query = query.Where("TRIM(COALESCE(description, '')) = ''")
+	default:
+		return nil, badRequest("has_description must be true or false")
+	}
+
+	// 搜索：商户、备注、分类
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = searchTransactions(query, q)
+	}
+
+	return query, nil
 }
 
 // GetTransaction 获取单个交易
@@ -76,7 +259,7 @@ func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 	transactionID := c.Param("id")
 
 	var transaction models.Transaction
-	if err := database.DB.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+	if err := database.DB.Preload("Tags").Preload("Splits").Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
 		return
 	}
@@ -94,31 +277,78 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
+	transaction, err := CreateTransactionForUser(userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// CreateTransactionForUser 校验请求并为用户创建交易，HTTP 接口和 MCP 工具共用；
+// 在同一数据库事务中执行，失败时不会留下自动创建的分类和标签
+func CreateTransactionForUser(userID uint, req CreateTransactionRequest) (*models.Transaction, error) {
+	var transaction *models.Transaction
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = createTransaction(tx, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// createTransaction 在 db（可以是事务）中校验并创建交易
+func createTransaction(db *gorm.DB, userID uint, req CreateTransactionRequest) (*models.Transaction, error) {
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
 	// 验证账户是否属于当前用户
 	var account models.Account
-	if err := database.DB.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
-		return
+	if err := db.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
+		return nil, badRequest("invalid account_id")
 	}
 
 	// 如果是转账，验证目标账户
 	if req.Type == models.TransactionTransfer {
 		if req.ToAccountID == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "to_account_id is required for transfer"})
-			return
+			return nil, badRequest("to_account_id is required for transfer")
 		}
 		var toAccount models.Account
-		if err := database.DB.Where("id = ? AND user_id = ?", *req.ToAccountID, userID).First(&toAccount).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to_account_id"})
-			return
+		if err := db.Where("id = ? AND user_id = ?", *req.ToAccountID, userID).First(&toAccount).Error; err != nil {
+			return nil, badRequest("invalid to_account_id")
 		}
+	} else if req.ToAmount != nil {
+		return nil, badRequest("to_amount is only allowed for transfer")
 	}
 
 	// 解析日期
 	transactionDate, err := time.Parse("2006-01-02", req.TransactionDate)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction_date format, use YYYY-MM-DD"})
-		return
+		return nil, badRequest("invalid transaction_date format, use YYYY-MM-DD")
+	}
+
+	// 自动分类规则
+	if !req.rulesApplied {
+		ruleSet, err := loadRules(db, userID)
+		if err != nil {
+			return nil, err
+		}
+		applyRulesToRequest(ruleSet, &req, false)
+	}
+
+	categoryID, category, err := resolveTransactionCategory(db, userID, req.Type, req.CategoryID, req.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	status := req.Status
+	if status == "" {
+		status = models.TransactionUncleared
 	}
 
 	transaction := models.Transaction{
@@ -127,27 +357,49 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		ToAccountID:     req.ToAccountID,
 		Type:            req.Type,
 		Amount:          req.Amount,
-		Category:        req.Category,
+		CategoryID:      categoryID,
+		Category:        category,
 		Merchant:        req.Merchant,
 		Description:     req.Description,
+		ExternalID:      req.externalID,
+		RecurringID:     req.recurringID,
+		Status:          status,
 		TransactionDate: transactionDate,
 	}
+	if err := checkReconciledPeriod(db, &transaction); err != nil {
+		return nil, err
+	}
+	if err := applyTransferAmount(db, &transaction, req.ToAmount); err != nil {
+		return nil, err
+	}
+	splits, err := buildSplits(db, userID, &transaction, req.Splits)
+	if err != nil {
+		return nil, err
+	}
+	transaction.Splits = splits
 
-	if err := database.DB.Create(&transaction).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create transaction"})
-		return
+	tags, err := findOrCreateTags(db, userID, req.Tags)
+	if err != nil {
+		return nil, err
 	}
+	transaction.Tags = tags
 
-	c.JSON(http.StatusCreated, transaction)
+	if err := db.Create(&transaction).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to create transaction"}
+	}
+
+	return &transaction, nil
 }
 
+// errTransactionReconciled 已对账的交易被锁定，需先删除对应的对账记录才能修改
+var errTransactionReconciled = &RequestError{Status: http.StatusConflict, Message: "transaction is reconciled and cannot be modified"}
+
 // UpdateTransaction 更新交易
 func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	transactionID := c.Param("id")
 
-	var transaction models.Transaction
-	if err := database.DB.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
 		return
 	}
@@ -158,26 +410,50 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		return
 	}
 
+	// 交易、拆分明细和标签分多次写入，放在同一事务中，失败时整体回滚
+	var transaction *models.Transaction
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = updateTransaction(tx, userID, uint(transactionID), req)
+		return err
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// updateTransaction 在 db（可以是事务）中按请求更新用户的交易，只修改请求中提供的字段
+func updateTransaction(db *gorm.DB, userID, transactionID uint, req UpdateTransactionRequest) (*models.Transaction, error) {
+	var transaction models.Transaction
+	if err := db.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusNotFound, Message: "transaction not found"}
+	}
+	if transaction.Status == models.TransactionReconciled {
+		return nil, errTransactionReconciled
+	}
+
 	// 更新字段
 	if req.AccountID != 0 {
 		// 验证账户
 		var account models.Account
-		if err := database.DB.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
-			return
+		if err := db.Where("id = ? AND user_id = ?", req.AccountID, userID).First(&account).Error; err != nil {
+			return nil, badRequest("invalid account_id")
 		}
 		transaction.AccountID = req.AccountID
 	}
 
 	if req.ToAccountID != nil {
 		var toAccount models.Account
-		if err := database.DB.Where("id = ? AND user_id = ?", *req.ToAccountID, userID).First(&toAccount).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to_account_id"})
-			return
+		if err := db.Where("id = ? AND user_id = ?", *req.ToAccountID, userID).First(&toAccount).Error; err != nil {
+			return nil, badRequest("invalid to_account_id")
 		}
 		transaction.ToAccountID = req.ToAccountID
 	}
 
+	oldType, oldAmount := transaction.Type, transaction.Amount
 	if req.Type != "" {
 		transaction.Type = req.Type
 
@@ -185,8 +461,7 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		if req.Type == models.TransactionTransfer {
 			// 如果改成 transfer，必须提供 to_account_id
 			if req.ToAccountID == nil && transaction.ToAccountID == nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "to_account_id is required when type is transfer"})
-				return
+				return nil, badRequest("to_account_id is required when type is transfer")
 			}
 			// 如果请求中提供了新的 to_account_id，已在上面验证过了
 		} else {
@@ -197,8 +472,18 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	if req.Amount > 0 {
 		transaction.Amount = req.Amount
 	}
-	if req.Category != "" {
-		transaction.Category = req.Category
+	// 分类：显式修改，或类型变化导致收支方向改变时按名称重新匹配
+	kindChanged := models.CategoryKindFor(oldType) != models.CategoryKindFor(transaction.Type)
+	if req.CategoryID != nil || req.Category != "" || (kindChanged && transaction.Category != "") {
+		name := req.Category
+		if req.CategoryID == nil && name == "" {
+			name = transaction.Category
+		}
+		categoryID, category, err := resolveTransactionCategory(db, userID, transaction.Type, req.CategoryID, name)
+		if err != nil {
+			return nil, err
+		}
+		transaction.CategoryID, transaction.Category = categoryID, category
 	}
 	if req.Merchant != "" {
 		transaction.Merchant = req.Merchant
@@ -209,35 +494,195 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 	if req.TransactionDate != "" {
 		transactionDate, err := time.Parse("2006-01-02", req.TransactionDate)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid transaction_date format"})
-			return
+			return nil, badRequest("invalid transaction_date format")
 		}
 		transaction.TransactionDate = transactionDate
 	}
+	if req.Status != "" {
+		transaction.Status = req.Status
+	}
+	if req.ToAmount != nil && transaction.Type != models.TransactionTransfer {
+		return nil, badRequest("to_amount is only allowed for transfer")
+	}
+	if err := applyTransferAmount(db, &transaction, req.ToAmount); err != nil {
+		return nil, err
+	}
 
-	if err := database.DB.Save(&transaction).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update transaction"})
-		return
+	// 类型或金额变化时按修改后的交易重新校验原有拆分行，收支方向改变时按名称重新匹配分类
+	if req.Splits == nil && (transaction.Type != oldType || transaction.Amount != oldAmount) {
+		var existing []models.TransactionSplit
+		if err := db.Where("transaction_id = ?", transaction.ID).Order("id").Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 {
+			splitReqs := make([]SplitRequest, len(existing))
+			for i, split := range existing {
+				splitReqs[i] = SplitRequest{Category: split.Category, Amount: split.Amount, Description: split.Description}
+				if !kindChanged {
+					splitReqs[i].CategoryID = split.CategoryID
+				}
+			}
+			req.Splits = &splitReqs
+		}
+	}
+	var splits []models.TransactionSplit
+	if req.Splits != nil {
+		var err error
+		if splits, err = buildSplits(db, userID, &transaction, *req.Splits); err != nil {
+			return nil, err
+		}
 	}
 
-	c.JSON(http.StatusOK, transaction)
+	// 改到已对账的期间会改变已对账的余额
+	if req.TransactionDate != "" || req.AccountID != 0 || req.ToAccountID != nil {
+		if err := checkReconciledPeriod(db, &transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := db.Save(&transaction).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+	}
+
+	if req.Splits != nil {
+		if err := db.Where("transaction_id = ?", transaction.ID).Delete(&models.TransactionSplit{}).Error; err != nil {
+			return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+		}
+		if len(splits) > 0 {
+			if err := db.Create(&splits).Error; err != nil {
+				return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+			}
+		}
+	}
+	if err := db.Where("transaction_id = ?", transaction.ID).Order("id").Find(&transaction.Splits).Error; err != nil {
+		return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+	}
+
+	if req.Tags != nil {
+		tags, err := findOrCreateTags(db, userID, *req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Model(&transaction).Association("Tags").Replace(tags); err != nil {
+			return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+		}
+	}
+	if err := db.Model(&transaction).Association("Tags").Find(&transaction.Tags); err != nil {
+		return nil, &RequestError{Status: http.StatusInternalServerError, Message: "failed to update transaction"}
+	}
+
+	return &transaction, nil
+}
+
+// applyTransferAmount 设置转账的目标账户入账金额：两个账户币种不同时必须有 to_amount（更新时可沿用原值），
+// 币种相同或非转账时清空，入账金额即 amount
+func applyTransferAmount(db *gorm.DB, transaction *models.Transaction, toAmount *models.Money) error {
+	if transaction.Type != models.TransactionTransfer || transaction.ToAccountID == nil {
+		transaction.ToAmount, transaction.ImpliedRate = nil, nil
+		return nil
+	}
+
+	crossCurrency, err := isCrossCurrency(db, transaction.AccountID, *transaction.ToAccountID)
+	if err != nil {
+		return err
+	}
+	if !crossCurrency {
+		if toAmount != nil && *toAmount != transaction.Amount {
+			return badRequest("to_amount must equal amount for transfers in the same currency")
+		}
+		transaction.ToAmount, transaction.ImpliedRate = nil, nil
+		return nil
+	}
+
+	if toAmount != nil {
+		transaction.ToAmount = toAmount
+	}
+	if transaction.ToAmount == nil {
+		return badRequest("to_amount is required for transfers between accounts with different currencies")
+	}
+	rate := math.Round(float64(*transaction.ToAmount)/float64(transaction.Amount)*1e6) / 1e6
+	transaction.ImpliedRate = &rate
+	return nil
+}
+
+// buildSplits 解析拆分行的分类并校验各行金额合计等于交易金额；只有收入和支出可以拆分
+func buildSplits(db *gorm.DB, userID uint, transaction *models.Transaction, reqs []SplitRequest) ([]models.TransactionSplit, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	if transaction.Type != models.TransactionIncome && transaction.Type != models.TransactionExpense {
+		return nil, badRequest("splits are only allowed for income and expense")
+	}
+
+	// 先校验金额，避免按名称自动创建分类后才失败
+	var total models.Money
+	for _, req := range reqs {
+		if req.Amount <= 0 {
+			return nil, badRequest("split amount must be positive")
+		}
+		total += req.Amount
+	}
+	if total != transaction.Amount {
+		return nil, badRequest("splits must sum to the transaction amount")
+	}
+
+	splits := make([]models.TransactionSplit, 0, len(reqs))
+	for _, req := range reqs {
+		categoryID, category, err := resolveTransactionCategory(db, userID, transaction.Type, req.CategoryID, req.Category)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, models.TransactionSplit{
+			TransactionID: transaction.ID,
+			CategoryID:    categoryID,
+			Category:      category,
+			Amount:        req.Amount,
+			Description:   req.Description,
+		})
+	}
+	return splits, nil
+}
+
+// isCrossCurrency 两个账户（含已删除）的币种是否不同
+func isCrossCurrency(db *gorm.DB, accountID, toAccountID uint) (bool, error) {
+	var currencies []string
+	if err := db.Unscoped().Model(&models.Account{}).Where("id IN ?", []uint{accountID, toAccountID}).
+		Distinct().Pluck("currency", &currencies).Error; err != nil {
+		return false, err
+	}
+	return len(currencies) > 1, nil
 }
 
 // DeleteTransaction 删除交易（软删除）
 func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 	userID := c.GetUint("user_id")
-	transactionID := c.Param("id")
 
-	var transaction models.Transaction
-	if err := database.DB.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+	transactionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "transaction not found"})
 		return
 	}
 
-	if err := database.DB.Delete(&transaction).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete transaction"})
+	if err := deleteTransaction(database.DB, userID, uint(transactionID)); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "transaction deleted successfully"})
 }
+
+// deleteTransaction 在 db（可以是事务）中软删除用户的交易
+func deleteTransaction(db *gorm.DB, userID, transactionID uint) error {
+	var transaction models.Transaction
+	if err := db.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+		return &RequestError{Status: http.StatusNotFound, Message: "transaction not found"}
+	}
+	if transaction.Status == models.TransactionReconciled {
+		return errTransactionReconciled
+	}
+
+	if err := db.Delete(&transaction).Error; err != nil {
+		return &RequestError{Status: http.StatusInternalServerError, Message: "failed to delete transaction"}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// ParseAlipayCSV 解析支付宝导出的 CSV 账单（GBK 编码，带说明性前言和尾注）
+//
+// 兼容新版（交易时间/交易分类/商品说明/金额）和旧版（交易创建时间/商品名称/金额（元））两种表头。
+// 不计收支的退款行按收入导入；其原订单在同一账单中已关闭（全额退款、未入账）时跳过，避免重复。
+func ParseAlipayCSV(data []byte) ([]Record, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readCSV(text)
+	if err != nil {
+		return nil, err
+	}
+	t, err := findTable(rows, "交易对方", "收/支")
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for i, row := range t.rows {
+		if !t.isDataRow(row) {
+			continue
+		}
+		records = append(records, parseAlipayRow(t, row, t.firstLine+i))
+	}
+
+	var closed []Record
+	for _, record := range records {
+		if record.SkipReason == skipTradeClosed {
+			closed = append(closed, record)
+		}
+	}
+	for i := range records {
+		if !records[i].refund || records[i].SkipReason != "" || records[i].Err != nil {
+			continue
+		}
+		for _, original := range closed {
+			if refundOf(records[i], original) {
+				records[i].SkipReason = "refund of a closed trade, original payment not imported"
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+// skipTradeClosed 交易关闭的订单没有实际资金变动
+const skipTradeClosed = "trade closed"
+
+func parseAlipayRow(t *table, row []string, line int) Record {
+	record := Record{
+		Line:        line,
+		Category:    dashToEmpty(t.get(row, "交易分类")),
+		Merchant:    dashToEmpty(t.get(row, "交易对方")),
+		Description: dashToEmpty(t.get(row, "商品说明", "商品名称")),
+		ExternalID:  t.get(row, "交易订单号", "交易号"),
+		orderID:     t.get(row, "商家订单号", "商户订单号"),
+	}
+
+	// 交易关闭的订单没有实际资金变动
+	status := t.get(row, "交易状态")
+	if strings.Contains(status, "关闭") {
+		record.SkipReason = skipTradeClosed
+		return record
+	}
+
+	txTime, err := parseTime(t.get(row, "交易时间", "交易创建时间", "付款时间"))
+	if err != nil {
+		record.Err = err
+		return record
+	}
+	record.Time = txTime
+
+	amount, err := parseAmount(t.get(row, "金额", "金额(元)"))
+	if err != nil {
+		record.Err = err
+		return record
+	}
+	if amount == 0 {
+		record.SkipReason = "zero amount"
+		return record
+	}
+	record.Amount = amount
+
+	direction := t.get(row, "收/支")
+	switch direction {
+	case "收入":
+		record.Type = models.TransactionIncome
+	case "支出":
+		record.Type = models.TransactionExpense
+	case "不计收支", "":
+		if strings.Contains(status, "退款") {
+			// 退款退回本账户，按收入记账
+			record.Type = models.TransactionIncome
+			record.refund = true
+			record.Category = "退款"
+		} else {
+			record.Type = models.TransactionTransfer
+		}
+	default:
+		record.Err = fmt.Errorf("unknown direction %q", direction)
+	}
+	return record
+}
@@ -0,0 +1,235 @@
+// Package importer 解析第三方账单（支付宝、微信）为统一的交易记录
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jasxu/fi_system/internal/models"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Source 账单来源
+type Source string
+
+const (
+	SourceAlipay Source = "alipay"
+	SourceWechat Source = "wechat"
+)
+
+// Record 账单中的一行，解析失败或需要跳过时 Err/SkipReason 非空
+type Record struct {
+	Line        int
+	Time        time.Time
+	Type        models.TransactionType
+	Amount      models.Money
+	Category    string
+	Merchant    string
+	Description string
+	ExternalID  string // 第三方交易单号，用于去重
+	Inbound     bool   // 仅转账：true 表示资金从对方账户转入本账户
+	orderID     string // 商户订单号，与交易单号一起用于关联退款和原订单
+	refund      bool   // 退款行，是否入账取决于同一账单中的原交易
+	netted      bool   // 已按净额扣减退款的支付行
+
+	SkipReason string
+	Err        error
+}
+
+// refundOf 判断退款行是否属于 original 的订单：交易单号或商户订单号相同，
+// 或退款单号以原单号为前缀（平台的退款单号通常由原单号加后缀构成）
+func refundOf(refund, original Record) bool {
+	for _, id := range []string{refund.ExternalID, refund.orderID} {
+		for _, originalID := range []string{original.ExternalID, original.orderID} {
+			if id != "" && originalID != "" && strings.HasPrefix(id, originalID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 行处理结果
+const (
+	StatusCreated = "created"
+	StatusSkipped = "skipped"
+	StatusFailed  = "failed"
+)
+
+// RowResult 单行导入结果
+type RowResult struct {
+	Line          int    `json:"line"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason,omitempty"`
+	TransactionID uint   `json:"transaction_id,omitempty"`
+}
+
+// Report 导入报告，所有来源共用
+type Report struct {
+	Source  Source      `json:"source"`
+	Total   int         `json:"total"`
+	Created int         `json:"created"`
+	Skipped int         `json:"skipped"`
+	Failed  int         `json:"failed"`
+	Rows    []RowResult `json:"rows"`
+}
+
+// Add 记录一行结果并更新计数
+func (r *Report) Add(row RowResult) {
+	r.Total++
+	switch row.Status {
+	case StatusCreated:
+		r.Created++
+	case StatusSkipped:
+		r.Skipped++
+	case StatusFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+var ErrHeaderNotFound = errors.New("header row not found, unsupported bill format")
+
+// decodeText 将 GBK 编码的内容转为 UTF-8，已是 UTF-8 的内容原样返回（去掉 BOM）
+func decodeText(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data, nil
+	}
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode GBK content: %w", err)
+	}
+	return decoded, nil
+}
+
+// readCSV 读取所有行，容忍列数不一致的前言/尾注行
+func readCSV(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read csv: %w", err)
+		}
+		rows = append(rows, record)
+	}
+	return rows, nil
+}
+
+// table 表头定位后的账单内容
+type table struct {
+	columns   map[string]int
+	rows      [][]string
+	firstLine int // rows[0] 在原文件中的行号（从 1 开始）
+}
+
+// findTable 查找同时包含 required 中所有列名的表头行
+func findTable(rows [][]string, required ...string) (*table, error) {
+	for i, row := range rows {
+		columns := make(map[string]int, len(row))
+		for j, cell := range row {
+			columns[normalizeHeader(cell)] = j
+		}
+		found := true
+		for _, name := range required {
+			if _, ok := columns[name]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return &table{columns: columns, rows: rows[i+1:], firstLine: i + 2}, nil
+		}
+	}
+	return nil, ErrHeaderNotFound
+}
+
+// get 按列名（可多个别名）取值
+func (t *table) get(row []string, names ...string) string {
+	for _, name := range names {
+		if idx, ok := t.columns[name]; ok && idx < len(row) {
+			return cleanCell(row[idx])
+		}
+	}
+	return ""
+}
+
+// isDataRow 判断是否为数据行（排除空行和尾部的分隔线/说明）
+func (t *table) isDataRow(row []string) bool {
+	if len(row) < len(t.columns)/2 {
+		return false
+	}
+	first := cleanCell(row[0])
+	return first != "" && !strings.HasPrefix(first, "---")
+}
+
+func normalizeHeader(s string) string {
+	s = cleanCell(s)
+	s = strings.ReplaceAll(s, "（", "(")
+	s = strings.ReplaceAll(s, "）", ")")
+	return s
+}
+
+func cleanCell(s string) string {
+	return strings.TrimSpace(strings.Trim(s, "\t "))
+}
+
+// parseAmount 精确解析金额，兼容 ¥ 前缀和千分位
+func parseAmount(s string) (models.Money, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimLeft(s, "¥￥")
+	amount, err := models.ParseMoney(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+var timeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02 15:04",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// parseTime 解析账单时间（按本地时间）
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	// XLSX 中的日期可能以序列号存储
+	if t, ok := excelSerialTime(s); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// dashToEmpty 账单里用 "/" 或 "-" 表示空值
+func dashToEmpty(s string) string {
+	if s == "/" || s == "-" {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// partialRefundPattern 匹配 "已退款(¥5.00)" / "已退款￥5.00" 中的退款金额
+var partialRefundPattern = regexp.MustCompile(`已退款\s*[(（]?\s*[¥￥]?\s*([0-9.,]+)`)
+
+// ParseWechat 解析微信支付导出的账单，自动识别 CSV 或 XLSX
+//
+// 退款处理：原支付行按净额记账（全额退款的跳过、部分退款扣减），按单号关联到本账单中已扣减支付的 "-退款" 行不再重复入账；
+// 原支付不在本账单中（如上期账单）的退款行按收入导入。零钱提现/零钱充值等不计收支的行按转账处理。
+func ParseWechat(data []byte) ([]Record, error) {
+	var rows [][]string
+	var err error
+	if isXLSX(data) {
+		rows, err = readXLSX(data)
+	} else {
+		var text []byte
+		text, err = decodeText(data)
+		if err == nil {
+			rows, err = readCSV(text)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := findTable(rows, "交易时间", "交易类型", "交易对方", "收/支", "金额(元)")
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for i, row := range t.rows {
+		if !t.isDataRow(row) {
+			continue
+		}
+		records = append(records, parseWechatRow(t, row, t.firstLine+i))
+	}
+
+	var netted []Record
+	for _, record := range records {
+		if record.netted {
+			netted = append(netted, record)
+		}
+	}
+	for i := range records {
+		if !records[i].refund || records[i].SkipReason != "" || records[i].Err != nil {
+			continue
+		}
+		for _, original := range netted {
+			if refundOf(records[i], original) {
+				records[i].SkipReason = "refund netted against original payment"
+				break
+			}
+		}
+	}
+	return records, nil
+}
+
+func parseWechatRow(t *table, row []string, line int) Record {
+	txType := t.get(row, "交易类型")
+	status := t.get(row, "当前状态")
+	record := Record{
+		Line:        line,
+		Merchant:    dashToEmpty(t.get(row, "交易对方")),
+		Description: dashToEmpty(t.get(row, "商品")),
+		ExternalID:  t.get(row, "交易单号"),
+		orderID:     t.get(row, "商户单号"),
+	}
+
+	// 退款行是否入账由 ParseWechat 按同一账单中是否有已扣减的原支付判断
+	refund := strings.Contains(txType, "退款")
+	if !refund {
+		record.netted = strings.Contains(status, "退款")
+		if strings.Contains(status, "已全额退款") {
+			record.SkipReason = "fully refunded"
+			return record
+		}
+		if strings.Contains(status, "失败") || strings.Contains(status, "关闭") || strings.Contains(status, "已撤销") {
+			record.SkipReason = "trade not completed"
+			return record
+		}
+	}
+
+	txTime, err := parseTime(t.get(row, "交易时间"))
+	if err != nil {
+		record.Err = err
+		return record
+	}
+	record.Time = txTime
+
+	amount, err := parseAmount(t.get(row, "金额(元)"))
+	if err != nil {
+		record.Err = err
+		return record
+	}
+
+	// 部分退款：按净额记账
+	if m := partialRefundPattern.FindStringSubmatch(status); m != nil && !refund {
+		refunded, err := parseAmount(m[1])
+		if err != nil {
+			record.Err = err
+			return record
+		}
+		amount -= refunded
+		if amount <= 0 {
+			record.SkipReason = "fully refunded"
+			return record
+		}
+	}
+	if amount == 0 {
+		record.SkipReason = "zero amount"
+		return record
+	}
+	record.Amount = amount
+
+	if refund {
+		record.Type = models.TransactionIncome
+		record.Category = "退款"
+		record.refund = true
+		return record
+	}
+
+	direction := t.get(row, "收/支")
+	switch {
+	case direction == "收入":
+		record.Type = models.TransactionIncome
+	case direction == "支出":
+		record.Type = models.TransactionExpense
+	case direction == "/" || direction == "":
+		// 零钱提现：零钱 → 银行卡；零钱充值：银行卡 → 零钱
+		record.Type = models.TransactionTransfer
+		record.Inbound = strings.Contains(txType, "充值")
+	default:
+		record.Err = fmt.Errorf("unknown direction %q", direction)
+	}
+	return record
+}
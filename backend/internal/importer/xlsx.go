@@ -0,0 +1,216 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isXLSX 通过 zip 文件头判断是否为 XLSX
+func isXLSX(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var sb strings.Builder
+	for _, run := range r.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref       string       `xml:"r,attr"`
+			Type      string       `xml:"t,attr"`
+			Value     string       `xml:"v"`
+			InlineStr xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"id,attr"` // r:id
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// firstSheetPath 通过 xl/workbook.xml 及其关系文件找到第一个工作表的路径，
+// 解析失败时退回到 xl/worksheets/ 下按名称排序的第一个 XML
+func firstSheetPath(files map[string]*zip.File) string {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	workbookFile, hasWorkbook := files["xl/workbook.xml"]
+	relsFile, hasRels := files["xl/_rels/workbook.xml.rels"]
+	if hasWorkbook && hasRels &&
+		decodeZipXML(workbookFile, &workbook) == nil && decodeZipXML(relsFile, &rels) == nil && len(workbook.Sheets) > 0 {
+		for _, rel := range rels.Relationships {
+			if rel.ID != workbook.Sheets[0].RelID {
+				continue
+			}
+			// Target 相对于 xl/，以 / 开头时为包内绝对路径
+			if strings.HasPrefix(rel.Target, "/") {
+				return path.Clean(strings.TrimPrefix(rel.Target, "/"))
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+
+	var sheets []string
+	for name := range files {
+		if strings.HasPrefix(name, "xl/worksheets/") && path.Dir(name) == "xl/worksheets" && strings.HasSuffix(name, ".xml") {
+			sheets = append(sheets, name)
+		}
+	}
+	if len(sheets) == 0 {
+		return ""
+	}
+	sort.Strings(sheets)
+	return sheets[0]
+}
+
+// readXLSX 读取工作簿第一个工作表的全部单元格（仅支持账单导出用到的字符串和数字）
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open xlsx: %w", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	sheetFile, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, fmt.Errorf("failed to open xlsx: worksheet not found")
+	}
+	var sheet xlsxSheet
+	if err := decodeZipXML(sheetFile, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, r := range sheet.Rows {
+		var row []string
+		for i, cell := range r.Cells {
+			// 引用缺失或不合法时按单元格顺序定位
+			col, ok := columnIndex(cell.Ref)
+			if !ok {
+				col = i
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err == nil && idx >= 0 && idx < len(shared.Items) {
+					row[col] = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				row[col] = cell.InlineStr.String()
+			default:
+				row[col] = cell.Value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// maxXLSXPartSize 单个 XML 部件解压后的大小上限，防止压缩炸弹
+const maxXLSXPartSize = 100 << 20
+
+// decodeZipXML 解压并解析 zip 中的 XML 部件，解压后超过 maxXLSXPartSize 时报错
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, maxXLSXPartSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	if len(content) > maxXLSXPartSize {
+		return fmt.Errorf("failed to read %s: content too large", f.Name)
+	}
+	if err := xml.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// maxXLSXColumns Excel 的最大列数（XFD）
+const maxXLSXColumns = 16384
+
+// columnIndex 将单元格引用（如 "AB12"）的列部分转为从 0 开始的下标；
+// 引用不是 "字母+行号" 形式或超出 Excel 列数时返回 false
+func columnIndex(ref string) (int, bool) {
+	col, letters := 0, 0
+	for _, ch := range strings.ToUpper(ref) {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		letters++
+		if col > maxXLSXColumns {
+			return 0, false
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	if _, err := strconv.Atoi(ref[letters:]); err != nil {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// excelSerialTime 将 Excel 日期序列号转为时间
+func excelSerialTime(s string) (time.Time, bool) {
+	serial, err := strconv.ParseFloat(s, 64)
+	if err != nil || serial <= 0 {
+		return time.Time{}, false
+	}
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local)
+	return base.Add(time.Duration(serial * float64(24*time.Hour))).Round(time.Second), true
+}
@@ -0,0 +1,214 @@
+package investment
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// ActionType 行动指令类型
+type ActionType string
+
+const (
+	ActionBaseInvest      ActionType = "base_invest"      // 基础定投
+	ActionBoostInvest     ActionType = "boost_invest"     // 加速包
+	ActionReplenishCash   ActionType = "replenish_cash"   // 补充活钱桶
+	ActionReplenishBuffer ActionType = "replenish_buffer" // 补充缓冲桶
+	ActionHold            ActionType = "hold"             // 暂停/禁止类提示，无金额
+)
+
+// Input 生成指令所需的全部信息
+type Input struct {
+	Date        time.Time
+	Currency    string // 金额的币种（用户本位币），用于指令文字
+	Stage       StageResult
+	BaseAmount  models.Money
+	Frequency   models.InvestmentFrequency
+	BoostAmount models.Money
+	Allocation  models.AssetAllocation
+}
+
+// AllocationAmount 单个资产类别的买入金额
+type AllocationAmount struct {
+	Asset  string       `json:"asset"`
+	Label  string       `json:"label"`
+	Amount models.Money `json:"amount"`
+}
+
+// Action 一条可执行的行动指令
+type Action struct {
+	Date        time.Time          `json:"date"`
+	Stage       Stage              `json:"stage"`
+	Type        ActionType         `json:"type"`
+	Instruction string             `json:"instruction"`
+	Amount      models.Money       `json:"amount"`
+	Breakdown   []AllocationAmount `json:"breakdown,omitempty"`
+}
+
+// assetLabels 常见资产类别的中文名
+var assetLabels = map[string]string{
+	"equity": "权益",
+	"stock":  "权益",
+	"bond":   "债基",
+	"gold":   "黄金",
+	"cash":   "现金",
+	"reit":   "REITs",
+	"crypto": "加密货币",
+}
+
+// PeriodStart 返回 date 所在定投周期的第一天（周一或每月 1 日，UTC 零点）
+func PeriodStart(date time.Time, frequency models.InvestmentFrequency) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if frequency == models.FrequencyWeekly {
+		offset := (int(day.Weekday()) + 6) % 7 // 周一为 0
+		return day.AddDate(0, 0, -offset)
+	}
+	return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Generate 根据阶段和定投配置生成本周期的行动指令
+//
+// 阶段 1：优先补充活钱桶，禁止定投；阶段 2：基础定投并补充缓冲桶；
+// 阶段 3：基础定投，配置了加速包时一并执行。加速包在阶段 1/2 明确提示不允许启用。
+func Generate(in Input) []Action {
+	date := PeriodStart(in.Date, in.Frequency)
+	period := "本月"
+	if in.Frequency == models.FrequencyWeekly {
+		period = "本周"
+	}
+
+	newAction := func(t ActionType, amount models.Money, instruction string) Action {
+		return Action{Date: date, Stage: in.Stage.Stage, Type: t, Amount: amount, Instruction: instruction}
+	}
+	format := func(amount models.Money) string { return FormatMoney(amount, in.Currency) }
+
+	var actions []Action
+	switch in.Stage.Stage {
+	case StageCashShortfall:
+		shortfall := in.Stage.Cash.Shortfall
+		actions = append(actions,
+			newAction(ActionReplenishCash, shortfall, fmt.Sprintf("优先补充活钱桶缺口 %s", format(shortfall))),
+			newAction(ActionHold, 0, fmt.Sprintf("%s暂停定投：活钱桶未达标", period)),
+		)
+	case StageBufferShortfall:
+		if invest, ok := investAction(newAction, format, ActionBaseInvest, period+"买入", in.BaseAmount, in.Allocation); ok {
+			actions = append(actions, invest)
+		}
+		shortfall := in.Stage.Buffer.Shortfall
+		actions = append(actions, newAction(ActionReplenishBuffer, shortfall, fmt.Sprintf("补充缓冲桶缺口 %s", format(shortfall))))
+	case StageFullyFunded:
+		if invest, ok := investAction(newAction, format, ActionBaseInvest, period+"买入", in.BaseAmount, in.Allocation); ok {
+			actions = append(actions, invest)
+		}
+		if boost, ok := investAction(newAction, format, ActionBoostInvest, period+"加速包买入", in.BoostAmount, in.Allocation); ok {
+			actions = append(actions, boost)
+		}
+	}
+
+	// 现金桶未全部达标时，加速包不允许启用
+	if in.Stage.Stage != StageFullyFunded && in.BoostAmount > 0 {
+		actions = append(actions, newAction(ActionHold, 0, fmt.Sprintf("%s不允许启用加速包", period)))
+	}
+	return actions
+}
+
+func investAction(newAction func(ActionType, models.Money, string) Action, format func(models.Money) string, t ActionType, verb string, amount models.Money, allocation models.AssetAllocation) (Action, bool) {
+	if amount <= 0 {
+		return Action{}, false
+	}
+	breakdown := SplitAmount(amount, allocation)
+	instruction := fmt.Sprintf("%s %s", verb, format(amount))
+	if len(breakdown) > 0 {
+		parts := make([]string, len(breakdown))
+		for i, b := range breakdown {
+			parts[i] = b.Label + " " + format(b.Amount)
+		}
+		instruction += "（" + strings.Join(parts, "、") + "）"
+	}
+
+	action := newAction(t, amount, instruction)
+	action.Breakdown = breakdown
+	return action, true
+}
+
+// SplitAmount 按配置比例拆分金额（按比例降序），舍入差额计入最后一项，保证合计等于总额
+func SplitAmount(amount models.Money, allocation models.AssetAllocation) []AllocationAmount {
+	if len(allocation) == 0 {
+		return nil
+	}
+
+	assets := make([]string, 0, len(allocation))
+	for asset, weight := range allocation {
+		if weight > 0 {
+			assets = append(assets, asset)
+		}
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if allocation[assets[i]] != allocation[assets[j]] {
+			return allocation[assets[i]] > allocation[assets[j]]
+		}
+		return assets[i] < assets[j]
+	})
+
+	remaining := amount
+	result := make([]AllocationAmount, len(assets))
+	for i, asset := range assets {
+		part := amount.MulRate(allocation[asset])
+		if i == len(assets)-1 {
+			part = remaining
+		}
+		remaining -= part
+		label, ok := assetLabels[asset]
+		if !ok {
+			label = asset
+		}
+		result[i] = AllocationAmount{Asset: asset, Label: label, Amount: part}
+	}
+	return result
+}
+
+// currencySymbols 常见币种的符号，其余币种以代码加空格作前缀（如 "USDT 12.50"）
+var currencySymbols = map[string]string{
+	"CNY": "¥",
+	"JPY": "¥",
+	"USD": "$",
+	"HKD": "HK$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
+// FormatMoney 按币种格式化为 "¥4,320" / "$12.50"，币种为空时按人民币
+func FormatMoney(amount models.Money, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	whole := int64(amount) / models.MoneyScale
+	cents := int64(amount) % models.MoneyScale
+	digits := strconv.FormatInt(whole, 10)
+	var sb strings.Builder
+	for i, ch := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(ch)
+	}
+	if cents > 0 {
+		sb.WriteString(fmt.Sprintf(".%02d", cents))
+	}
+	prefix := "¥"
+	if currency != "" {
+		if symbol, ok := currencySymbols[currency]; ok {
+			prefix = symbol
+		} else {
+			prefix = currency + " "
+		}
+	}
+	return sign + prefix + sb.String()
+}
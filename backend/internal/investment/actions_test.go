@@ -0,0 +1,179 @@
+package investment
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+func TestPeriodStart(t *testing.T) {
+	utc8 := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		name      string
+		date      time.Time
+		frequency models.InvestmentFrequency
+		want      string
+	}{
+		{"monthly mid-month", time.Date(2026, 10, 17, 15, 30, 0, 0, time.UTC), models.FrequencyMonthly, "2026-10-01"},
+		{"monthly first day", time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), models.FrequencyMonthly, "2026-10-01"},
+		{"monthly last day", time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC), models.FrequencyMonthly, "2026-02-01"},
+		{"monthly uses local calendar day", time.Date(2026, 11, 1, 1, 0, 0, 0, utc8), models.FrequencyMonthly, "2026-11-01"},
+		{"weekly monday", time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC), models.FrequencyWeekly, "2026-10-12"},
+		{"weekly saturday", time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC), models.FrequencyWeekly, "2026-10-12"},
+		{"weekly sunday belongs to previous monday", time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), models.FrequencyWeekly, "2026-10-12"},
+		{"weekly across month", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), models.FrequencyWeekly, "2026-10-26"},
+		{"weekly across year", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), models.FrequencyWeekly, "2026-12-28"},
+		{"empty frequency is monthly", time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), "", "2026-10-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PeriodStart(tt.date, tt.frequency)
+			if got.Format("2006-01-02") != tt.want || got.Location() != time.UTC || got.Hour() != 0 {
+				t.Errorf("PeriodStart(%v, %q) = %v, want %s 00:00 UTC", tt.date, tt.frequency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAmount(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     models.Money
+		allocation models.AssetAllocation
+		want       []AllocationAmount
+	}{
+		{"no allocation", 100000, nil, nil},
+		{
+			"exact split ordered by weight",
+			100000,
+			models.AssetAllocation{"gold": 0.1, "equity": 0.7, "bond": 0.2},
+			[]AllocationAmount{
+				{Asset: "equity", Label: "权益", Amount: 70000},
+				{Asset: "bond", Label: "债基", Amount: 20000},
+				{Asset: "gold", Label: "黄金", Amount: 10000},
+			},
+		},
+		{
+			"rounding remainder goes to last item",
+			10000,
+			models.AssetAllocation{"a": 1.0 / 3, "b": 1.0 / 3, "c": 1.0 / 3},
+			[]AllocationAmount{
+				{Asset: "a", Label: "a", Amount: 3333},
+				{Asset: "b", Label: "b", Amount: 3333},
+				{Asset: "c", Label: "c", Amount: 3334},
+			},
+		},
+		{
+			"zero weights are skipped",
+			5000,
+			models.AssetAllocation{"equity": 1, "bond": 0},
+			[]AllocationAmount{{Asset: "equity", Label: "权益", Amount: 5000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitAmount(tt.amount, tt.allocation)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitAmount(%d, %v) = %+v, want %+v", tt.amount, tt.allocation, got, tt.want)
+			}
+			var total models.Money
+			for _, part := range got {
+				total += part.Amount
+			}
+			if len(got) > 0 && total != tt.amount {
+				t.Errorf("parts sum to %d, want %d", total, tt.amount)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	date := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	stage := func(s Stage, cashShortfall, bufferShortfall models.Money) StageResult {
+		return StageResult{Stage: s, Cash: Bucket{Shortfall: cashShortfall}, Buffer: Bucket{Shortfall: bufferShortfall}}
+	}
+	type want struct {
+		Type        ActionType
+		Amount      models.Money
+		Instruction string
+	}
+	tests := []struct {
+		name string
+		in   Input
+		want []want
+	}{
+		{
+			"cash shortfall pauses investing",
+			Input{Date: date, Stage: stage(StageCashShortfall, 150000, 0), BaseAmount: 100000, Frequency: models.FrequencyMonthly},
+			[]want{
+				{ActionReplenishCash, 150000, "优先补充活钱桶缺口 ¥1,500"},
+				{ActionHold, 0, "本月暂停定投：活钱桶未达标"},
+			},
+		},
+		{
+			"boost is refused before buckets are funded",
+			Input{Date: date, Stage: stage(StageBufferShortfall, 0, 20050), BaseAmount: 50000, BoostAmount: 30000, Frequency: models.FrequencyWeekly},
+			[]want{
+				{ActionBaseInvest, 50000, "本周买入 ¥500"},
+				{ActionReplenishBuffer, 20050, "补充缓冲桶缺口 ¥200.50"},
+				{ActionHold, 0, "本周不允许启用加速包"},
+			},
+		},
+		{
+			"fully funded runs base and boost with breakdown",
+			Input{
+				Date: date, Currency: "USD", Stage: stage(StageFullyFunded, 0, 0), BaseAmount: 100000, BoostAmount: 50000,
+				Frequency: models.FrequencyMonthly, Allocation: models.AssetAllocation{"equity": 0.6, "bond": 0.4},
+			},
+			[]want{
+				{ActionBaseInvest, 100000, "本月买入 $1,000（权益 $600、债基 $400）"},
+				{ActionBoostInvest, 50000, "本月加速包买入 $500（权益 $300、债基 $200）"},
+			},
+		},
+		{
+			"zero base amount produces no invest action",
+			Input{Date: date, Stage: stage(StageFullyFunded, 0, 0), Frequency: models.FrequencyMonthly},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := Generate(tt.in)
+			var got []want
+			for _, a := range actions {
+				got = append(got, want{a.Type, a.Amount, a.Instruction})
+				if a.Stage != tt.in.Stage.Stage {
+					t.Errorf("action stage = %d, want %d", a.Stage, tt.in.Stage.Stage)
+				}
+				if !a.Date.Equal(PeriodStart(tt.in.Date, tt.in.Frequency)) {
+					t.Errorf("action date = %v, want period start", a.Date)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount   models.Money
+		currency string
+		want     string
+	}{
+		{432000, "CNY", "¥4,320"},
+		{1250, "", "¥12.50"},
+		{123456789, "USD", "$1,234,567.89"},
+		{-5, "EUR", "-€0.05"},
+		{100000, "USDT", "USDT 1,000"},
+		{0, "HKD", "HK$0"},
+	}
+	for _, tt := range tests {
+		if got := FormatMoney(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("FormatMoney(%d, %q) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Package investment 定投纪律：现金桶阶段判定和行动指令生成，不依赖数据库和 HTTP
+package investment
+
+import "github.com/jasxu/fi_system/internal/models"
+
+// Stage 定投阶段
+type Stage int
+
+const (
+	StageCashShortfall   Stage = 1 // 活钱桶未达标：禁止定投
+	StageBufferShortfall Stage = 2 // 活钱桶达标、缓冲桶未达标：仅基础定投
+	StageFullyFunded     Stage = 3 // 两个现金桶均达标：可启用加速包
+)
+
+// 现金桶目标未单独配置时按月支出的倍数计算
+const (
+	DefaultCashReserveMonths   = 1.5
+	DefaultBufferReserveMonths = 3.0
+)
+
+// Bucket 单个现金桶的状态
+type Bucket struct {
+	Balance   models.Money `json:"balance"`
+	Target    models.Money `json:"target"`
+	Shortfall models.Money `json:"shortfall"`
+	Funded    bool         `json:"funded"`
+}
+
+// StageResult 阶段判定结果
+type StageResult struct {
+	Stage       Stage  `json:"stage"`
+	Description string `json:"description"`
+	Cash        Bucket `json:"cash_bucket"`   // 活钱桶（高流动性账户）
+	Buffer      Bucket `json:"buffer_bucket"` // 缓冲桶（中流动性账户）
+}
+
+// Targets 根据配置返回两个现金桶的目标，配置为 0 时按月支出倍数计算
+func Targets(monthlyExpense, cashTarget, bufferTarget models.Money) (models.Money, models.Money) {
+	if cashTarget <= 0 {
+		cashTarget = monthlyExpense.MulRate(DefaultCashReserveMonths)
+	}
+	if bufferTarget <= 0 {
+		bufferTarget = monthlyExpense.MulRate(DefaultBufferReserveMonths)
+	}
+	return cashTarget, bufferTarget
+}
+
+// DetermineStage 根据活钱桶和缓冲桶余额判定阶段
+func DetermineStage(cashBalance, bufferBalance, cashTarget, bufferTarget models.Money) StageResult {
+	result := StageResult{
+		Cash:   newBucket(cashBalance, cashTarget),
+		Buffer: newBucket(bufferBalance, bufferTarget),
+	}
+
+	switch {
+	case !result.Cash.Funded:
+		result.Stage = StageCashShortfall
+		result.Description = "活钱桶未达标，禁止定投"
+	case !result.Buffer.Funded:
+		result.Stage = StageBufferShortfall
+		result.Description = "活钱桶已达标，缓冲桶未达标，仅执行基础定投"
+	default:
+		result.Stage = StageFullyFunded
+		result.Description = "两个现金桶均已达标，可启用加速包"
+	}
+	return result
+}
+
+func newBucket(balance, target models.Money) Bucket {
+	bucket := Bucket{
+		Balance: balance,
+		Target:  target,
+		Funded:  balance >= target,
+	}
+	if !bucket.Funded {
+		bucket.Shortfall = target - balance
+	}
+	return bucket
+}
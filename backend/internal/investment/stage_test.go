@@ -0,0 +1,55 @@
+package investment
+
+import (
+	"testing"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+func TestTargets(t *testing.T) {
+	tests := []struct {
+		name                  string
+		expense, cash, buffer models.Money
+		wantCash, wantBuffer  models.Money
+	}{
+		{"defaults from monthly expense", 1000000, 0, 0, 1500000, 3000000},
+		{"configured targets win", 1000000, 200000, 500000, 200000, 500000},
+		{"negative targets fall back", 1000000, -1, -1, 1500000, 3000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cash, buffer := Targets(tt.expense, tt.cash, tt.buffer)
+			if cash != tt.wantCash || buffer != tt.wantBuffer {
+				t.Errorf("Targets() = %d, %d, want %d, %d", cash, buffer, tt.wantCash, tt.wantBuffer)
+			}
+		})
+	}
+}
+
+func TestDetermineStage(t *testing.T) {
+	tests := []struct {
+		name                           string
+		cash, buffer                   models.Money
+		wantStage                      Stage
+		wantCashShort, wantBufferShort models.Money
+	}{
+		{"cash short", 50000, 900000, StageCashShortfall, 100000, 0},
+		{"cash exactly funded, buffer short", 150000, 100000, StageBufferShortfall, 0, 200000},
+		{"both funded", 150000, 300000, StageFullyFunded, 0, 0},
+		{"both short reports both shortfalls", 0, 0, StageCashShortfall, 150000, 300000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetermineStage(tt.cash, tt.buffer, 150000, 300000)
+			if got.Stage != tt.wantStage {
+				t.Errorf("stage = %d, want %d", got.Stage, tt.wantStage)
+			}
+			if got.Cash.Shortfall != tt.wantCashShort || got.Buffer.Shortfall != tt.wantBufferShort {
+				t.Errorf("shortfalls = %d, %d, want %d, %d", got.Cash.Shortfall, got.Buffer.Shortfall, tt.wantCashShort, tt.wantBufferShort)
+			}
+			if got.Cash.Funded != (tt.wantCashShort == 0) || got.Buffer.Funded != (tt.wantBufferShort == 0) {
+				t.Errorf("funded = %v, %v", got.Cash.Funded, got.Buffer.Funded)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package mcp 通过 stdio 实现 Model Context Protocol，供 Claude Desktop 调用记账工具
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// ProtocolVersion 实现的 MCP 协议版本
+const ProtocolVersion = "2024-11-05"
+
+const (
+	serverName    = "fi_system"
+	serverVersion = "0.1.0"
+)
+
+// JSON-RPC 错误码
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server 以固定用户身份处理 MCP 请求
+type Server struct {
+	userID uint
+	tools  []tool
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewServer 创建以 userID 身份执行工具的 MCP Server
+func NewServer(userID uint) *Server {
+	return &Server{userID: userID, tools: financeTools()}
+}
+
+// Serve 从 in 逐行读取 JSON-RPC 消息并将响应写入 out，直到 in 关闭
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		s.handleMessage(line)
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handleMessage(line []byte) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		s.writeError(json.RawMessage("null"), codeParseError, "parse error")
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.writeError(req.ID, codeInvalidRequest, "invalid request")
+		return
+	}
+
+	// 没有 id 的是通知，不需要响应
+	isNotification := len(req.ID) == 0
+
+	result, rpcErr := s.dispatch(req)
+	if isNotification {
+		return
+	}
+	if rpcErr != nil {
+		s.writeError(req.ID, rpcErr.Code, rpcErr.Message)
+		return
+	}
+	s.write(response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+func (s *Server) dispatch(req request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid initialize params"}
+		}
+	}
+
+	return map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]string{
+			"name":    serverName,
+			"version": serverVersion,
+		},
+	}, nil
+}
+
+func (s *Server) writeError(id json.RawMessage, code int, message string) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	s.write(response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func (s *Server) write(resp response) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("mcp: failed to encode response: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.out.Write(append(data, '\n')); err != nil {
+		log.Printf("mcp: failed to write response: %v", err)
+	}
+}
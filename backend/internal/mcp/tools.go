@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jasxu/fi_system/internal/handlers"
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+type tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	call func(userID uint, args json.RawMessage) (interface{}, error)
+}
+
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+func (s *Server) listTools() interface{} {
+	return map[string]interface{}{"tools": s.tools}
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid tools/call params"}
+	}
+
+	for _, t := range s.tools {
+		if t.Name != p.Name {
+			continue
+		}
+		args := p.Arguments
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+
+		// 工具执行失败以 isError 结果返回，让模型能看到原因并修正参数
+		result, err := t.call(s.userID, args)
+		if err != nil {
+			return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		text, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return toolResult{Content: []textContent{{Type: "text", Text: "failed to encode result"}}, IsError: true}, nil
+		}
+		return toolResult{Content: []textContent{{Type: "text", Text: string(text)}}}, nil
+	}
+	return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", p.Name)}
+}
+
+func financeTools() []tool {
+	return []tool{
+		{
+			Name:        "create_transaction",
+			Description: "Record a transaction (income, expense, transfer or investment) on one of the user's accounts.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"account_id":       prop("integer", "Account ID, see list_accounts"),
+				"to_account_id":    prop("integer", "Destination account ID, required for transfer"),
+				"type":             enumProp("Transaction type", "income", "expense", "transfer", "investment"),
+				"amount":           prop("number", "Positive amount in the account currency"),
+				"to_amount":        prop("number", "Amount received by the destination account, required for transfers between accounts with different currencies"),
+				"category":         prop("string", "Category, e.g. 餐饮, 交通, 购物"),
+				"merchant":         prop("string", "Merchant or counterparty"),
+				"description":      prop("string", "Free-form note"),
+				"transaction_date": prop("string", "Date in YYYY-MM-DD, defaults to today"),
+			}, "account_id", "type", "amount"),
+			call: createTransaction,
+		},
+		{
+			Name:        "get_account_balance",
+			Description: "Get the current balance of one account.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"account_id": prop("integer", "Account ID, see list_accounts"),
+			}, "account_id"),
+			call: getAccountBalance,
+		},
+		{
+			Name:        "list_accounts",
+			Description: "List all accounts of the user with their type, currency, current balance and the balance converted to the base currency.",
+			InputSchema: objectSchema(map[string]interface{}{}),
+			call:        listAccounts,
+		},
+		{
+			Name:        "get_spending_summary",
+			Description: "Summarize income and expenses by category for a date range (transfers excluded). Defaults to the current month.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"start_date": prop("string", "Start date YYYY-MM-DD (inclusive)"),
+				"end_date":   prop("string", "End date YYYY-MM-DD (inclusive)"),
+			}),
+			call: getSpendingSummary,
+		},
+	}
+}
+
+func createTransaction(userID uint, args json.RawMessage) (interface{}, error) {
+	var req handlers.CreateTransactionRequest
+	if err := json.Unmarshal(args, &req); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if req.TransactionDate == "" {
+		req.TransactionDate = time.Now().Format("2006-01-02")
+	}
+	return handlers.CreateTransactionForUser(userID, req)
+}
+
+func getAccountBalance(userID uint, args json.RawMessage) (interface{}, error) {
+	var p struct {
+		AccountID uint `json:"account_id"`
+	}
+	if err := json.Unmarshal(args, &p); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	return handlers.GetAccountWithBalance(userID, p.AccountID)
+}
+
+func listAccounts(userID uint, _ json.RawMessage) (interface{}, error) {
+	return handlers.ListAccountsWithBalance(userID)
+}
+
+func getSpendingSummary(userID uint, args json.RawMessage) (interface{}, error) {
+	var p struct {
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+	}
+	if err := json.Unmarshal(args, &p); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+	var err error
+	if p.StartDate != "" {
+		if start, err = time.Parse("2006-01-02", p.StartDate); err != nil {
+			return nil, fmt.Errorf("invalid start_date format, use YYYY-MM-DD")
+		}
+	}
+	if p.EndDate != "" {
+		if end, err = time.Parse("2006-01-02", p.EndDate); err != nil {
+			return nil, fmt.Errorf("invalid end_date format, use YYYY-MM-DD")
+		}
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end_date must not be before start_date")
+	}
+	return handlers.SummarizeCategories(userID, start, end)
+}
+
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func prop(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+func enumProp(description string, values ...models.TransactionType) map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": description, "enum": values}
+}
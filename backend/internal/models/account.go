@@ -26,15 +26,17 @@ const (
 )
 
 type Account struct {
-	ID             uint           `gorm:"primarykey" json:"id"`
-	UserID         uint           `gorm:"not null;index" json:"user_id"`
-	Name           string         `gorm:"not null;size:200" json:"name"`
-	Type           AccountType    `gorm:"not null;size:50" json:"type"`
-	Currency       string         `gorm:"not null;size:10;default:CNY" json:"currency"`
-	LiquidityLevel LiquidityLevel `gorm:"size:10;default:low" json:"liquidity_level"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	UserID             uint           `gorm:"not null;index" json:"user_id"`
+	Name               string         `gorm:"not null;size:200" json:"name"`
+	Type               AccountType    `gorm:"not null;size:50" json:"type"`
+	Currency           string         `gorm:"not null;size:10;default:CNY" json:"currency"`
+	LiquidityLevel     LiquidityLevel `gorm:"size:10;default:low" json:"liquidity_level"`
+	OpeningBalance     Money          `gorm:"not null;type:integer;default:0" json:"opening_balance"` // 期初余额，不计入收支
+	OpeningBalanceDate *time.Time     `json:"opening_balance_date,omitempty"`                         // 期初余额的截止日期，之前的交易已包含在期初余额中
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 关联
 	User User `gorm:"foreignKey:UserID" json:"-"`
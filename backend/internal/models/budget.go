@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+type BudgetPeriod string
+
+const (
+	BudgetWeekly  BudgetPeriod = "weekly"
+	BudgetMonthly BudgetPeriod = "monthly"
+	BudgetYearly  BudgetPeriod = "yearly"
+)
+
+// PeriodStart 返回 date 所在周期的第一天（周从周一开始）
+func (p BudgetPeriod) PeriodStart(date time.Time) time.Time {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	switch p {
+	case BudgetWeekly:
+		return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+	case BudgetYearly:
+		return time.Date(date.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// NextPeriodStart 返回下一个周期的第一天，start 须为周期起始日
+func (p BudgetPeriod) NextPeriodStart(start time.Time) time.Time {
+	switch p {
+	case BudgetWeekly:
+		return start.AddDate(0, 0, 7)
+	case BudgetYearly:
+		return start.AddDate(1, 0, 0)
+	default:
+		return start.AddDate(0, 1, 0)
+	}
+}
+
+// Budget 分类预算，支出按分类及其全部子分类统计
+type Budget struct {
+	ID         uint           `gorm:"primarykey" json:"id"`
+	UserID     uint           `gorm:"not null;index" json:"user_id"`
+	CategoryID uint           `gorm:"not null;index" json:"category_id"`
+	Amount     Money          `gorm:"not null;type:integer" json:"amount"` // 每个周期的预算额（本位币）
+	Period     BudgetPeriod   `gorm:"not null;size:20" json:"period"`
+	Rollover   bool           `gorm:"not null" json:"rollover"`   // 上期结余（或超支）计入本期
+	StartDate  time.Time      `gorm:"not null" json:"start_date"` // 首个周期的起始日，结转从此累计
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User     User     `gorm:"foreignKey:UserID" json:"-"`
+	Category Category `gorm:"foreignKey:CategoryID" json:"-"`
+}
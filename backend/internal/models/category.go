@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+type CategoryKind string
+
+const (
+	CategoryIncome  CategoryKind = "income"
+	CategoryExpense CategoryKind = "expense"
+)
+
+// CategoryKindFor 交易类型对应的分类类型：收入归入收入分类，其余（支出、投资、转账）归入支出分类
+func CategoryKindFor(t TransactionType) CategoryKind {
+	if t == TransactionIncome {
+		return CategoryIncome
+	}
+	return CategoryExpense
+}
+
+// Category 用户自定义分类，支持父子层级，汇总时子分类金额计入父分类
+type Category struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id,omitempty"`
+	Name      string         `gorm:"not null;size:100" json:"name"`
+	Kind      CategoryKind   `gorm:"not null;size:20" json:"kind"`
+	Icon      string         `gorm:"size:50" json:"icon,omitempty"`
+	Color     string         `gorm:"size:20" json:"color,omitempty"`
+	Archived  bool           `gorm:"not null;default:false" json:"archived"` // 归档后不再用于新交易，历史数据保留
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User   User      `gorm:"foreignKey:UserID" json:"-"`
+	Parent *Category `gorm:"foreignKey:ParentID" json:"-"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+// ExchangeRate 汇率：1 单位 FromCurrency 兑换 Rate 单位 ToCurrency，自 Date 起生效直到同币种对的下一条记录；
+// 同一用户同一币种对每天只有一条（含已删除的记录）
+type ExchangeRate struct {
+	ID           uint           `gorm:"primarykey" json:"id"`
+	UserID       uint           `gorm:"not null;uniqueIndex:idx_exchange_rate_pair" json:"user_id"`
+	FromCurrency string         `gorm:"not null;size:10;uniqueIndex:idx_exchange_rate_pair" json:"from"`
+	ToCurrency   string         `gorm:"not null;size:10;uniqueIndex:idx_exchange_rate_pair" json:"to"`
+	Date         time.Time      `gorm:"not null;uniqueIndex:idx_exchange_rate_pair" json:"date"`
+	Rate         float64        `gorm:"not null" json:"rate"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
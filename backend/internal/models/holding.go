@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+type AssetType string
+
+const (
+	AssetTypeStock  AssetType = "stock"
+	AssetTypeFund   AssetType = "fund"
+	AssetTypeCrypto AssetType = "crypto"
+	AssetTypeBond   AssetType = "bond"
+	AssetTypeCash   AssetType = "cash"
+	AssetTypeOther  AssetType = "other"
+)
+
+type Holding struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	UserID         uint           `gorm:"not null;index" json:"user_id"`
+	AccountID      uint           `gorm:"not null;uniqueIndex:idx_holding_account_asset_symbol" json:"account_id"`
+	AssetType      AssetType      `gorm:"not null;size:50;uniqueIndex:idx_holding_account_asset_symbol" json:"asset_type"`
+	Symbol         string         `gorm:"not null;size:50;uniqueIndex:idx_holding_account_asset_symbol" json:"symbol"`
+	Name           string         `gorm:"size:200" json:"name,omitempty"`
+	Quantity       float64        `gorm:"not null;type:decimal(28,8)" json:"quantity"`
+	CostBasisTotal Money          `gorm:"not null;type:integer" json:"cost_basis_total"`
+	Currency       string         `gorm:"not null;size:10;default:CNY" json:"currency"`
+	LastPrice      *float64       `gorm:"type:decimal(28,8)" json:"last_price,omitempty"`
+	LastPriceAt    *time.Time     `json:"last_price_at,omitempty"`
+	MarketValue    *Money         `gorm:"type:integer" json:"market_value,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Account Account `gorm:"foreignKey:AccountID" json:"-"`
+}
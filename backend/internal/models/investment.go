@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+	"gorm.io/gorm"
+)
+
+type InvestmentFrequency string
+
+const (
+	FrequencyWeekly  InvestmentFrequency = "weekly"
+	FrequencyMonthly InvestmentFrequency = "monthly"
+)
+
+// AssetAllocation 资产配置比例，如 {"equity": 0.7, "bond": 0.2, "gold": 0.1}
+type AssetAllocation map[string]float64
+
+// Value 以 JSON 文本存储
+func (a AssetAllocation) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 从 JSON 文本读取
+func (a *AssetAllocation) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = AssetAllocation{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("unsupported asset allocation value")
+	}
+	return json.Unmarshal(data, a)
+}
+
+type InvestmentSettings struct {
+	ID                      uint                `gorm:"primarykey" json:"id"`
+	UserID                  uint                `gorm:"not null;uniqueIndex" json:"user_id"`
+	MonthlyIncome           Money               `gorm:"not null;type:integer;default:0" json:"monthly_income"`
+	MonthlyExpense          Money               `gorm:"not null;type:integer;default:0" json:"monthly_expense"`
+	CashReserveTarget       Money               `gorm:"not null;type:integer;default:0" json:"cash_reserve_target"`   // 活钱桶目标
+	BufferReserveTarget     Money               `gorm:"not null;type:integer;default:0" json:"buffer_reserve_target"` // 缓冲桶目标
+	BaseInvestmentAmount    Money               `gorm:"not null;type:integer;default:0" json:"base_investment_amount"`
+	BaseInvestmentFrequency InvestmentFrequency `gorm:"not null;size:20;default:monthly" json:"base_investment_frequency"`
+	BoostInvestmentAmount   Money               `gorm:"not null;type:integer;default:0" json:"boost_investment_amount"` // 加速包，仅阶段 3 启用
+	AssetAllocation         AssetAllocation     `gorm:"type:text" json:"asset_allocation"`
+	CreatedAt               time.Time           `json:"created_at"`
+	UpdatedAt               time.Time           `json:"updated_at"`
+	DeletedAt               gorm.DeletedAt      `gorm:"index" json:"-"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+type InvestmentAction struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	ActionDate  time.Time      `gorm:"not null;index" json:"action_date"` // 所属定投周期的第一天
+	Stage       int            `gorm:"not null" json:"stage"`
+	ActionType  string         `gorm:"not null;size:50" json:"action_type"`
+	Instruction string         `gorm:"not null;type:text" json:"instruction"`
+	Amount      Money          `gorm:"not null;type:integer;default:0" json:"amount"`
+	Breakdown   MoneyBreakdown `gorm:"type:text" json:"breakdown,omitempty"` // 按资产类别拆分的金额
+	Executed    bool           `gorm:"not null;default:false" json:"executed"`
+	ExecutedAt  *time.Time     `json:"executed_at,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
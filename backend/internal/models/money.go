@@ -0,0 +1,217 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money 金额，以最小货币单位（分）存储为整数，避免浮点累加误差
+//
+// JSON 中仍以两位小数的数字表示（如 12.34），解析时按字符串精确转换，超过两位小数视为错误。
+type Money int64
+
+// MoneyScale 每个货币单位包含的最小单位数
+const MoneyScale = 100
+
+var ErrMoneyPrecision = errors.New("amount must have at most 2 decimal places")
+
+// ParseMoney 精确解析十进制金额字符串，如 "1,234.5"、"-0.01"
+func ParseMoney(s string) (Money, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" {
+		return 0, errors.New("empty amount")
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" && (!hasFrac || frac == "") {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	// 符号只允许出现在最前面，整数和小数部分只能是数字
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if len(frac) > 2 {
+		// 允许末尾多余的 0，如 "1.500"
+		if strings.Trim(frac[2:], "0") != "" {
+			return 0, ErrMoneyPrecision
+		}
+		frac = frac[:2]
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q out of range", s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if units > (math.MaxInt64-cents)/MoneyScale {
+		return 0, fmt.Errorf("amount %q out of range", s)
+	}
+
+	m := Money(units*MoneyScale + cents)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+// isDigits 判断字符串是否只含 ASCII 数字（空串视为是）
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MoneyFromFloat 将浮点数四舍五入到分，仅用于比例计算等本身不精确的场景
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * MoneyScale))
+}
+
+// Float64 转为浮点数（仅用于比例、百分比计算）
+func (m Money) Float64() float64 {
+	return float64(m) / MoneyScale
+}
+
+// MulRate 按比例缩放并四舍五入到分
+func (m Money) MulRate(rate float64) Money {
+	return Money(math.Round(float64(m) * rate))
+}
+
+// String 返回两位小数的十进制表示
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/MoneyScale, v%MoneyScale)
+}
+
+// MarshalJSON 输出为 JSON 数字，如 12.34
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON 接受 JSON 数字或字符串
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		s = str
+	} else if strings.ContainsAny(s, "eE") {
+		// 科学计数法先转为普通小数
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid amount %q", s)
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value 以整数（分）写入数据库
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan 从数据库读取；兼容聚合结果中出现的浮点数
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("unsupported money value %T", value)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*m = Money(i)
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid money value %q", s)
+	}
+	*m = Money(math.Round(f))
+	return nil
+}
+
+// MoneyBreakdown 按名称拆分的金额，以 JSON 文本存储
+type MoneyBreakdown map[string]Money
+
+// Value 以 JSON 文本存储
+func (b MoneyBreakdown) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 从 JSON 文本读取
+func (b *MoneyBreakdown) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*b = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("unsupported money breakdown value")
+	}
+	return json.Unmarshal(data, b)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		// 基本格式
+		{in: "0", want: 0},
+		{in: "12", want: 1200},
+		{in: "12.3", want: 1230},
+		{in: "12.34", want: 1234},
+		{in: ".5", want: 50},
+		{in: "5.", want: 500},
+		{in: "  7.00  ", want: 700},
+		// 千分位
+		{in: "1,234.5", want: 123450},
+		{in: "1,234,567.89", want: 123456789},
+		// 精度
+		{in: "1.500", want: 150},
+		{in: "0.010", want: 1},
+		{in: "1.234", wantErr: true},
+		{in: "0.001", wantErr: true},
+		// 符号
+		{in: "-0.01", want: -1},
+		{in: "+5", want: 500},
+		{in: "-1,000", want: -100000},
+		{in: "++5", wantErr: true},
+		{in: "--5", wantErr: true},
+		{in: "+-5", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "-.+5", wantErr: true},
+		// 溢出
+		{in: "92233720368547758.07", want: 9223372036854775807},
+		{in: "-92233720368547758.07", want: -9223372036854775807},
+		{in: "92233720368547758.08", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		// 格式错误
+		{in: "", wantErr: true},
+		{in: " ", wantErr: true},
+		{in: ",", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1 000", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "¥12", wantErr: true},
+		{in: "１２", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyPrecisionError(t *testing.T) {
+	if _, err := ParseMoney("1.234"); !errors.Is(err, ErrMoneyPrecision) {
+		t.Errorf("ParseMoney(\"1.234\") error = %v, want ErrMoneyPrecision", err)
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `12.34`, want: 1234},
+		{in: `"12.34"`, want: 1234},
+		{in: `"1,234"`, want: 123400},
+		{in: `1e2`, want: 10000},
+		{in: `-0.5`, want: -50},
+		{in: `12.345`, wantErr: true},
+		{in: `"1.+5"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	data, err := json.Marshal(struct{ A, B Money }{A: 1234, B: -5})
+	if err != nil || string(data) != `{"A":12.34,"B":-0.05}` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+}
+
+func TestMoneyMulRate(t *testing.T) {
+	tests := []struct {
+		m    Money
+		rate float64
+		want Money
+	}{
+		{10000, 0.7, 7000},
+		{1, 0.5, 1},
+		{3333, 1.5, 5000},
+		{-1000, 7.1234, -7123},
+	}
+	for _, tt := range tests {
+		if got := tt.m.MulRate(tt.rate); got != tt.want {
+			t.Errorf("Money(%d).MulRate(%v) = %d, want %d", tt.m, tt.rate, got, tt.want)
+		}
+	}
+}
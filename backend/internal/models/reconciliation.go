@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationOpen      ReconciliationStatus = "open"
+	ReconciliationCompleted ReconciliationStatus = "completed"
+)
+
+// Reconciliation 账单对账：记录某账户在某日的账单余额，完成时将已核对的交易锁定为 reconciled
+type Reconciliation struct {
+	ID               uint                 `gorm:"primarykey" json:"id"`
+	UserID           uint                 `gorm:"not null;index" json:"user_id"`
+	AccountID        uint                 `gorm:"not null;index" json:"account_id"`
+	StatementDate    time.Time            `gorm:"not null" json:"statement_date"`
+	StatementBalance Money                `gorm:"not null;type:integer" json:"statement_balance"` // 账单上的余额（账户币种）
+	Status           ReconciliationStatus `gorm:"not null;size:20;default:open" json:"status"`
+	CompletedAt      *time.Time           `json:"completed_at,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt       `gorm:"index" json:"-"`
+
+	// 关联
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Account Account `gorm:"foreignKey:AccountID" json:"-"`
+}
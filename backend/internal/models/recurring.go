@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+// RecurringTransaction 周期交易模板，由后台调度按 RRule 生成交易
+type RecurringTransaction struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"size:100" json:"name,omitempty"`
+
+	// 交易模板
+	AccountID   uint            `gorm:"not null;index" json:"account_id"`
+	ToAccountID *uint           `json:"to_account_id,omitempty"` // 仅 transfer 类型使用
+	Type        TransactionType `gorm:"not null;size:50" json:"type"`
+	Amount      Money           `gorm:"not null;type:integer" json:"amount"`
+	ToAmount    *Money          `gorm:"type:integer" json:"to_amount,omitempty"` // 跨币种转账时目标账户的入账金额
+	CategoryID  *uint           `json:"category_id,omitempty"`
+	Category    string          `gorm:"size:100" json:"category,omitempty"`
+	Merchant    string          `gorm:"size:200" json:"merchant,omitempty"`
+	Description string          `gorm:"size:500" json:"description,omitempty"`
+	Tags        StringList      `gorm:"type:text" json:"tags"`
+
+	// 排程
+	RRule     string     `gorm:"column:rrule;not null;size:200" json:"rrule"` // 如 FREQ=MONTHLY;BYMONTHDAY=5
+	StartDate time.Time  `gorm:"not null" json:"start_date"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	NextRun   *time.Time `gorm:"index" json:"next_run,omitempty"` // 为空表示已结束
+	LastRun   *time.Time `json:"last_run,omitempty"`              // 最近一次已生成的日期
+	Active    bool       `gorm:"not null" json:"active"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User    User    `gorm:"foreignKey:UserID" json:"-"`
+	Account Account `gorm:"foreignKey:AccountID" json:"-"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+	"gorm.io/gorm"
+)
+
+// StringList 字符串列表，以 JSON 文本存储
+type StringList []string
+
+// Value 以 JSON 文本存储
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 从 JSON 文本读取
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return errors.New("unsupported string list value")
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Rule 自动分类规则：所有已设置的条件同时满足时执行动作。
+// 多条规则按 Priority 升序执行，分类和商户名以先命中的规则为准，标签累加。
+type Rule struct {
+	ID             uint   `gorm:"primarykey" json:"id"`
+	UserID         uint   `gorm:"not null;index" json:"user_id"`
+	Name           string `gorm:"not null;size:100" json:"name"`
+	Priority       int    `gorm:"not null;default:0" json:"priority"` // 数值越小越先执行
+	Enabled        bool   `gorm:"not null" json:"enabled"`
+	StopProcessing bool   `gorm:"not null;default:false" json:"stop_processing"` // 命中后不再执行后续规则
+
+	// 条件
+	MerchantPattern    string          `gorm:"size:200" json:"merchant_pattern,omitempty"`    // 商户正则
+	DescriptionPattern string          `gorm:"size:200" json:"description_pattern,omitempty"` // 备注正则
+	Keyword            string          `gorm:"size:100" json:"keyword,omitempty"`             // 商户或备注包含关键词（不区分大小写）
+	MinAmount          *Money          `gorm:"type:integer" json:"min_amount,omitempty"`
+	MaxAmount          *Money          `gorm:"type:integer" json:"max_amount,omitempty"`
+	AccountID          *uint           `gorm:"index" json:"account_id,omitempty"`
+	TransactionType    TransactionType `gorm:"size:50" json:"transaction_type,omitempty"`
+
+	// 动作
+	SetCategoryID *uint      `json:"set_category_id,omitempty"`
+	SetMerchant   string     `gorm:"size:200" json:"set_merchant,omitempty"` // 商户名规范化
+	AddTags       StringList `gorm:"type:text" json:"add_tags"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
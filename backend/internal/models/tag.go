@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+	"gorm.io/gorm"
+)
+
+// Tag 跨分类的标签（如“2025 日本旅行”），与交易多对多关联
+type Tag struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	Name      string         `gorm:"not null;size:50" json:"name"`
+	Color     string         `gorm:"size:20" json:"color,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 关联
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -14,23 +14,54 @@ const (
 	TransactionInvestment TransactionType = "investment"
 )
 
+// TransactionStatus 对账状态：已对账的交易被锁定，不能修改或删除
+type TransactionStatus string
+
+const (
+	TransactionUncleared  TransactionStatus = "uncleared"
+	TransactionCleared    TransactionStatus = "cleared"    // 已与账单核对
+	TransactionReconciled TransactionStatus = "reconciled" // 已随对账完成锁定
+)
+
 type Transaction struct {
-	ID              uint            `gorm:"primarykey" json:"id"`
-	UserID          uint            `gorm:"not null;index" json:"user_id"`
-	AccountID       uint            `gorm:"not null;index" json:"account_id"`
-	ToAccountID     *uint           `gorm:"index" json:"to_account_id,omitempty"` // 仅 transfer 类型使用
-	Type            TransactionType `gorm:"not null;size:50" json:"type"`
-	Amount          float64         `gorm:"not null;type:decimal(20,2)" json:"amount"`
-	Category        string          `gorm:"size:100" json:"category,omitempty"`
-	Merchant        string          `gorm:"size:200" json:"merchant,omitempty"`
-	Description     string          `gorm:"size:500" json:"description,omitempty"`
-	TransactionDate time.Time       `gorm:"not null;index" json:"transaction_date"`
-	CreatedAt       time.Time       `json:"created_at"`
-	UpdatedAt       time.Time       `json:"updated_at"`
-	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"-"`
+	ID               uint              `gorm:"primarykey" json:"id"`
+	UserID           uint              `gorm:"not null;index" json:"user_id"`
+	AccountID        uint              `gorm:"not null;index" json:"account_id"`
+	ToAccountID      *uint             `gorm:"index" json:"to_account_id,omitempty"` // 仅 transfer 类型使用
+	Type             TransactionType   `gorm:"not null;size:50" json:"type"`
+	Amount           Money             `gorm:"not null;type:integer" json:"amount"`     // 单位：分
+	ToAmount         *Money            `gorm:"type:integer" json:"to_amount,omitempty"` // 跨币种转账时目标账户的入账金额
+	ImpliedRate      *float64          `json:"implied_rate,omitempty"`                  // 跨币种转账的隐含汇率 = to_amount / amount
+	CategoryID       *uint             `gorm:"index" json:"category_id,omitempty"`
+	Category         string            `gorm:"size:100" json:"category,omitempty"` // 分类名称，与 CategoryID 保持一致
+	Merchant         string            `gorm:"size:200" json:"merchant,omitempty"`
+	Description      string            `gorm:"size:500" json:"description,omitempty"`
+	ExternalID       string            `gorm:"size:100;index" json:"external_id,omitempty"` // 导入账单的第三方交易单号
+	RecurringID      *uint             `gorm:"uniqueIndex:idx_transaction_recurring_date" json:"recurring_id,omitempty"`
+	Status           TransactionStatus `gorm:"not null;size:20;default:uncleared;index" json:"status"`
+	ReconciliationID *uint             `gorm:"index" json:"reconciliation_id,omitempty"` // 锁定该交易的对账记录
+	TransactionDate  time.Time         `gorm:"not null;index;uniqueIndex:idx_transaction_recurring_date" json:"transaction_date"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt    `gorm:"index" json:"-"`
 
 	// 关联
-	User      User     `gorm:"foreignKey:UserID" json:"-"`
-	Account   Account  `gorm:"foreignKey:AccountID" json:"-"`
-	ToAccount *Account `gorm:"foreignKey:ToAccountID" json:"-"`
+	User      User               `gorm:"foreignKey:UserID" json:"-"`
+	Account   Account            `gorm:"foreignKey:AccountID" json:"-"`
+	ToAccount *Account           `gorm:"foreignKey:ToAccountID" json:"-"`
+	Tags      []Tag              `gorm:"many2many:transaction_tags" json:"tags,omitempty"`
+	Splits    []TransactionSplit `gorm:"foreignKey:TransactionID" json:"splits,omitempty"`
+}
+
+// TransactionSplit 交易拆分行：一笔收支按多个分类拆分，各行金额合计等于交易金额；分类汇总有拆分行时按拆分行计入
+type TransactionSplit struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	TransactionID uint           `gorm:"not null;index" json:"transaction_id"`
+	CategoryID    *uint          `gorm:"index" json:"category_id,omitempty"`
+	Category      string         `gorm:"size:100" json:"category,omitempty"` // 分类名称，与 CategoryID 保持一致
+	Amount        Money          `gorm:"not null;type:integer" json:"amount"`
+	Description   string         `gorm:"size:500" json:"description,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
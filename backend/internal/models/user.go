@@ -9,6 +9,7 @@ type User struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
 	Username     string         `gorm:"uniqueIndex;not null;size:100" json:"username"`
 	PasswordHash string         `gorm:"not null;size:255" json:"-"`
+	BaseCurrency string         `gorm:"not null;size:10;default:CNY" json:"base_currency"` // 报表和总资产的折算币种
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
@@ -0,0 +1,207 @@
+// Package recurrence 解析 RRULE 风格的周期规则并计算发生日期，不依赖数据库和 HTTP
+//
+// 支持的子集：FREQ=DAILY|WEEKLY|MONTHLY|YEARLY;INTERVAL=n;BYDAY=MO,WE（仅 WEEKLY）;BYMONTHDAY=d（仅 MONTHLY，-1 表示月末）。
+// 所有日期按 UTC 零点处理；月份中不存在的日期（如 31 日、2 月 29 日）取该月最后一天。
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency 重复频率
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Rule 解析后的周期规则
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday // WEEKLY：每周哪几天，为空时取开始日期的星期
+	ByMonthDay int            // MONTHLY：每月几号，0 取开始日期，负数从月末倒数
+}
+
+// Parse 解析 RRULE 字符串（可带 "RRULE:" 前缀）
+func Parse(s string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(s)), "RRULE:")
+	if s == "" {
+		return rule, errors.New("empty rrule")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return rule, fmt.Errorf("invalid rrule part %q", part)
+		}
+		key, value := kv[0], kv[1]
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(value)
+			default:
+				return rule, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 1000 {
+				return rule, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			seen := make(map[time.Weekday]bool)
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("invalid BYDAY %q", code)
+				}
+				if !seen[day] {
+					seen[day] = true
+					rule.ByDay = append(rule.ByDay, day)
+				}
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < -31 || n > 31 {
+				return rule, fmt.Errorf("invalid BYMONTHDAY %q", value)
+			}
+			rule.ByMonthDay = n
+		default:
+			return rule, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, errors.New("FREQ is required")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return rule, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if rule.ByMonthDay != 0 && rule.Freq != Monthly {
+		return rule, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	// 按周一开始的顺序排列，便于按日期顺序生成
+	sort.Slice(rule.ByDay, func(i, j int) bool { return weekdayIndex(rule.ByDay[i]) < weekdayIndex(rule.ByDay[j]) })
+	return rule, nil
+}
+
+// Date 截断为 UTC 零点日期
+func Date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// First 返回从 start（含）开始的第一个发生日期
+func (r Rule) First(start time.Time) time.Time {
+	start = Date(start)
+	return r.Next(start, start.AddDate(0, 0, -1))
+}
+
+// Next 返回从 start 开始的排程中严格晚于 after 的第一个发生日期
+func (r Rule) Next(start, after time.Time) time.Time {
+	start, after = Date(start), Date(after)
+	if after.Before(start) {
+		after = start.AddDate(0, 0, -1)
+	}
+
+	// 从 after 所在周期的前一个周期开始查找，避免从头遍历
+	k := r.periodsBetween(start, after)/r.Interval - 1
+	if k < 0 {
+		k = 0
+	}
+	for ; ; k++ {
+		for _, d := range r.occurrences(start, k*r.Interval) {
+			if !d.Before(start) && d.After(after) {
+				return d
+			}
+		}
+	}
+}
+
+// periodsBetween start 与 t 之间相隔的自然周期数
+func (r Rule) periodsBetween(start, t time.Time) int {
+	switch r.Freq {
+	case Daily:
+		return int(t.Sub(start).Hours() / 24)
+	case Weekly:
+		return int(weekStart(t).Sub(weekStart(start)).Hours() / 24 / 7)
+	case Monthly:
+		return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	default:
+		return t.Year() - start.Year()
+	}
+}
+
+// occurrences 第 offset 个自然周期内的发生日期（按日期升序）
+func (r Rule) occurrences(start time.Time, offset int) []time.Time {
+	switch r.Freq {
+	case Daily:
+		return []time.Time{start.AddDate(0, 0, offset)}
+	case Weekly:
+		week := weekStart(start).AddDate(0, 0, 7*offset)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		result := make([]time.Time, len(days))
+		for i, day := range days {
+			result[i] = week.AddDate(0, 0, weekdayIndex(day))
+		}
+		return result
+	case Monthly:
+		month := time.Date(start.Year(), start.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		day := r.ByMonthDay
+		if day == 0 {
+			day = start.Day()
+		}
+		last := daysIn(month)
+		if day < 0 {
+			day = last + day + 1
+			if day < 1 {
+				day = 1
+			}
+		}
+		if day > last {
+			day = last
+		}
+		return []time.Time{month.AddDate(0, 0, day-1)}
+	default:
+		month := time.Date(start.Year()+offset, start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		day := start.Day()
+		if last := daysIn(month); day > last {
+			day = last
+		}
+		return []time.Time{month.AddDate(0, 0, day-1)}
+	}
+}
+
+// weekdayIndex 周一为 0
+func weekdayIndex(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+func weekStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, -weekdayIndex(t.Weekday()))
+}
+
+func daysIn(month time.Time) int {
+	return month.AddDate(0, 1, -1).Day()
+}
@@ -0,0 +1,157 @@
+package recurrence
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// schedule 从 start 起连续取 n 个发生日期
+func schedule(r Rule, start time.Time, n int) []string {
+	result := make([]string, 0, n)
+	d := r.First(start)
+	for i := 0; i < n; i++ {
+		result = append(result, d.Format("2006-01-02"))
+		d = r.Next(start, d)
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rrule   string
+		want    Rule
+		wantErr string
+	}{
+		{"daily", "FREQ=DAILY", Rule{Freq: Daily, Interval: 1}, ""},
+		{"prefix and lowercase", " rrule:freq=monthly;interval=3 ", Rule{Freq: Monthly, Interval: 3}, ""},
+		{"trailing separator", "FREQ=YEARLY;", Rule{Freq: Yearly, Interval: 1}, ""},
+		{
+			"byday sorted from monday and deduplicated",
+			"FREQ=WEEKLY;BYDAY=SU,FR,MO,FR",
+			Rule{Freq: Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday, time.Friday, time.Sunday}},
+			"",
+		},
+		{"month end", "FREQ=MONTHLY;BYMONTHDAY=-1", Rule{Freq: Monthly, Interval: 1, ByMonthDay: -1}, ""},
+		{"empty", "  ", Rule{}, "empty rrule"},
+		{"missing freq", "INTERVAL=2", Rule{}, "FREQ is required"},
+		{"unknown freq", "FREQ=HOURLY", Rule{}, "unsupported FREQ"},
+		{"malformed part", "FREQ=DAILY;INTERVAL", Rule{}, "invalid rrule part"},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0", Rule{}, "invalid INTERVAL"},
+		{"interval too large", "FREQ=DAILY;INTERVAL=1001", Rule{}, "invalid INTERVAL"},
+		{"bad weekday", "FREQ=WEEKLY;BYDAY=XX", Rule{}, "invalid BYDAY"},
+		{"byday outside weekly", "FREQ=MONTHLY;BYDAY=MO", Rule{}, "BYDAY is only supported"},
+		{"zero monthday", "FREQ=MONTHLY;BYMONTHDAY=0", Rule{}, "invalid BYMONTHDAY"},
+		{"monthday out of range", "FREQ=MONTHLY;BYMONTHDAY=32", Rule{}, "invalid BYMONTHDAY"},
+		{"monthday outside monthly", "FREQ=YEARLY;BYMONTHDAY=1", Rule{}, "BYMONTHDAY is only supported"},
+		// 结束条件由模板的 end_date 控制，规则本身不接受 COUNT/UNTIL
+		{"count not supported", "FREQ=DAILY;COUNT=3", Rule{}, "unsupported rrule part \"COUNT\""},
+		{"until not supported", "FREQ=DAILY;UNTIL=20261231", Rule{}, "unsupported rrule part \"UNTIL\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.rrule)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want %q", tt.rrule, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rrule, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.rrule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		rrule string
+		start string
+		want  []string
+	}{
+		{"daily", "FREQ=DAILY", "2026-12-30", []string{"2026-12-30", "2026-12-31", "2027-01-01"}},
+		{"daily interval", "FREQ=DAILY;INTERVAL=10", "2026-10-17", []string{"2026-10-17", "2026-10-27", "2026-11-06"}},
+		{"weekly defaults to start weekday", "FREQ=WEEKLY", "2026-10-17", []string{"2026-10-17", "2026-10-24", "2026-10-31"}},
+		{
+			"weekly byday skips days before start",
+			"FREQ=WEEKLY;BYDAY=MO,WE,FR", "2026-10-14",
+			[]string{"2026-10-14", "2026-10-16", "2026-10-19", "2026-10-21"},
+		},
+		{
+			"weekly byday with interval",
+			"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU", "2026-10-12",
+			[]string{"2026-10-13", "2026-10-18", "2026-10-27", "2026-11-01"},
+		},
+		{
+			"monthly clamps to month end and recovers",
+			"FREQ=MONTHLY", "2027-01-31",
+			[]string{"2027-01-31", "2027-02-28", "2027-03-31", "2027-04-30"},
+		},
+		{"monthly leap february", "FREQ=MONTHLY", "2028-01-30", []string{"2028-01-30", "2028-02-29", "2028-03-30"}},
+		{
+			"monthly last day",
+			"FREQ=MONTHLY;BYMONTHDAY=-1", "2026-11-15",
+			[]string{"2026-11-30", "2026-12-31", "2027-01-31", "2027-02-28"},
+		},
+		{"monthly bymonthday before start", "FREQ=MONTHLY;BYMONTHDAY=5", "2026-10-17", []string{"2026-11-05", "2026-12-05"}},
+		{"monthly bymonthday clamped", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-09-01", []string{"2026-09-30", "2026-10-31", "2026-11-30"}},
+		{"monthly interval across year", "FREQ=MONTHLY;INTERVAL=5", "2026-10-17", []string{"2026-10-17", "2027-03-17", "2027-08-17"}},
+		{"yearly leap day", "FREQ=YEARLY", "2028-02-29", []string{"2028-02-29", "2029-02-28", "2030-02-28", "2031-02-28", "2032-02-29"}},
+		{"yearly interval", "FREQ=YEARLY;INTERVAL=2", "2026-10-17", []string{"2026-10-17", "2028-10-17"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rrule)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.rrule, err)
+			}
+			got := schedule(rule, date(tt.start), len(tt.want))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schedule(%q, %s) = %v, want %v", tt.rrule, tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	monthEnd := Rule{Freq: Monthly, Interval: 1}
+	tests := []struct {
+		name  string
+		rule  Rule
+		start string
+		after string
+		want  string
+	}{
+		{"after before start returns first", monthEnd, "2027-01-31", "2026-06-01", "2027-01-31"},
+		{"after on start", monthEnd, "2027-01-31", "2027-01-31", "2027-02-28"},
+		{"after between occurrences", monthEnd, "2027-01-31", "2027-03-01", "2027-03-31"},
+		{"far after start", monthEnd, "2027-01-31", "2037-02-27", "2037-02-28"},
+		{"time of day ignored", Rule{Freq: Daily, Interval: 1}, "2026-10-17", "2026-10-17", "2026-10-18"},
+		{"interval counted from start", Rule{Freq: Daily, Interval: 3}, "2026-10-17", "2026-10-21", "2026-10-23"},
+		{"weekly interval counted from start week", Rule{Freq: Weekly, Interval: 2}, "2026-10-17", "2026-10-25", "2026-10-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := date(tt.start).Add(15 * time.Hour)
+			got := tt.rule.Next(start, date(tt.after).Add(9*time.Hour))
+			if got.Format("2006-01-02") != tt.want || got.Location() != time.UTC || got.Hour() != 0 {
+				t.Errorf("Next(%s, %s) = %v, want %s 00:00 UTC", tt.start, tt.after, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package rules 自动分类规则引擎：按优先级匹配交易，给出分类、规范化商户名和标签，不依赖数据库和 HTTP
+package rules
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jasxu/fi_system/internal/models"
+)
+
+// Rule 编译后的规则
+type Rule struct {
+	models.Rule
+	CategoryKind models.CategoryKind // 目标分类的类型，为空表示分类不可用（已归档或已删除）
+
+	merchant    *regexp.Regexp
+	description *regexp.Regexp
+	keyword     string
+}
+
+// Input 参与匹配的交易字段
+type Input struct {
+	AccountID   uint
+	Type        models.TransactionType
+	Amount      models.Money
+	Merchant    string
+	Description string
+}
+
+// Result 规则执行结果；CategoryID 为 nil、Merchant 为空表示不修改
+type Result struct {
+	CategoryID *uint    `json:"category_id,omitempty"`
+	Merchant   string   `json:"merchant,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	RuleIDs    []uint   `json:"rule_ids"`
+}
+
+// Validate 校验规则：正则可编译、金额区间有效，且至少有一个条件和一个动作
+func Validate(rule models.Rule) error {
+	_, err := Compile(rule, "")
+	return err
+}
+
+// Compile 编译规则中的正则表达式
+func Compile(rule models.Rule, categoryKind models.CategoryKind) (*Rule, error) {
+	compiled := &Rule{Rule: rule, CategoryKind: categoryKind, keyword: strings.ToLower(strings.TrimSpace(rule.Keyword))}
+
+	var err error
+	if rule.MerchantPattern != "" {
+		if compiled.merchant, err = regexp.Compile(rule.MerchantPattern); err != nil {
+			return nil, errors.New("invalid merchant_pattern: " + err.Error())
+		}
+	}
+	if rule.DescriptionPattern != "" {
+		if compiled.description, err = regexp.Compile(rule.DescriptionPattern); err != nil {
+			return nil, errors.New("invalid description_pattern: " + err.Error())
+		}
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return nil, errors.New("min_amount must not exceed max_amount")
+	}
+
+	hasCondition := compiled.merchant != nil || compiled.description != nil || compiled.keyword != "" ||
+		rule.MinAmount != nil || rule.MaxAmount != nil || rule.AccountID != nil || rule.TransactionType != ""
+	if !hasCondition {
+		return nil, errors.New("rule must have at least one condition")
+	}
+	if rule.SetCategoryID == nil && rule.SetMerchant == "" && len(rule.AddTags) == 0 {
+		return nil, errors.New("rule must have at least one action")
+	}
+	return compiled, nil
+}
+
+// Match 判断交易是否满足规则的全部条件
+func (r *Rule) Match(in Input) bool {
+	if r.merchant != nil && !r.merchant.MatchString(in.Merchant) {
+		return false
+	}
+	if r.description != nil && !r.description.MatchString(in.Description) {
+		return false
+	}
+	if r.keyword != "" &&
+		!strings.Contains(strings.ToLower(in.Merchant), r.keyword) &&
+		!strings.Contains(strings.ToLower(in.Description), r.keyword) {
+		return false
+	}
+	if r.MinAmount != nil && in.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && in.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.AccountID != nil && *r.AccountID != in.AccountID {
+		return false
+	}
+	if r.TransactionType != "" && r.TransactionType != in.Type {
+		return false
+	}
+	return true
+}
+
+// Apply 按顺序执行规则（调用方负责按优先级排序）：分类和商户名取第一个给出值的规则，标签去重累加；转账不设置分类
+func Apply(rules []*Rule, in Input) Result {
+	result := Result{RuleIDs: []uint{}}
+	seenTags := make(map[string]bool)
+
+	for _, r := range rules {
+		if !r.Enabled || !r.Match(in) {
+			continue
+		}
+		result.RuleIDs = append(result.RuleIDs, r.ID)
+
+		// 分类只应用于收支方向一致的交易，转账不是收支，不设置分类
+		if result.CategoryID == nil && r.SetCategoryID != nil && in.Type != models.TransactionTransfer &&
+			r.CategoryKind == models.CategoryKindFor(in.Type) {
+			id := *r.SetCategoryID
+			result.CategoryID = &id
+		}
+		if result.Merchant == "" && r.SetMerchant != "" {
+			result.Merchant = r.SetMerchant
+		}
+		for _, tag := range r.AddTags {
+			if tag = strings.TrimSpace(tag); tag != "" && !seenTags[tag] {
+				seenTags[tag] = true
+				result.Tags = append(result.Tags, tag)
+			}
+		}
+
+		if r.StopProcessing {
+			break
+		}
+	}
+	return result
+}
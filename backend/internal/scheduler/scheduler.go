@@ -0,0 +1,56 @@
+// Package scheduler 按固定间隔在后台执行任务，支持优雅停止
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Job 后台任务，ctx 取消时应尽快返回
+type Job func(ctx context.Context)
+
+// Scheduler 启动时立即执行一次任务，之后每隔 interval 执行；同一时间只有一个任务在运行
+type Scheduler struct {
+	interval time.Duration
+	job      Job
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(interval time.Duration, job Job) *Scheduler {
+	return &Scheduler{interval: interval, job: job}
+}
+
+// Start 在后台 goroutine 中开始调度
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.job(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 取消调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}